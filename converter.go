@@ -2,6 +2,7 @@ package main
 
 
 import (
+  "encoding/csv"
   "fmt"
   "log"
   "sort"
@@ -9,37 +10,48 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 )
 
+// ConvertBsonToCsv returns the documents as csv.  The header is every
+// field found in any document, sorted, since fields like the birth
+// details are only stored when they were given.
 func ConvertBsonToCsv(data []bson.D) (string, error) {
-  headers := []string{}
-  rowValues := map[string]string{}
-  results := ""
-	for index, doc := range data {
-    log.Printf("parsing row: %v\n", doc)
-    row := ""
-    for _, value := range doc {
-      // {name mark}
-      nameAndValue := fmt.Sprintf("%v", value)
-      nameAndValue = strings.TrimLeft(nameAndValue, "{")
-      nameAndValue = strings.TrimRight(nameAndValue, "}")
-      parts := strings.SplitN(nameAndValue, " ", 2)
-      if parts[0] == "account" || parts[0] == "_id" {
+  headerSet := map[string]bool{}
+  for _, doc := range data {
+    for _, element := range doc {
+      if element.Key == "account" || element.Key == "_id" {
         continue
       }
-      if index == 0 {
-        headers = append(headers, parts[0])
-      }
-      rowValues[parts[0]] = parts[1]
+      headerSet[element.Key] = true
     }
+  }
+  headers := []string{}
+  for header := range headerSet {
+    headers = append(headers, header)
+  }
+  sort.Strings(headers)
 
-    if index == 0 {
-      sort.Strings(headers)
+  // Fields like the death note are free text and can hold commas, the
+  // csv writer quotes them.
+  var results strings.Builder
+  writer := csv.NewWriter(&results)
+  if err := writer.Write(headers); err != nil {
+    return "", err
+  }
+	for _, doc := range data {
+    log.Printf("parsing row: %v\n", doc)
+    rowValues := map[string]string{}
+    for _, element := range doc {
+      rowValues[element.Key] = fmt.Sprintf("%v", element.Value)
     }
+
+    row := []string{}
     for _, header := range headers {
-      row += rowValues[header] + ","
+      row = append(row, rowValues[header])
     }
-    row = strings.TrimSuffix(row, ",") + "\n"
     log.Printf("Adding row: %v\n", row)
-    results += row
+    if err := writer.Write(row); err != nil {
+      return "", err
+    }
   }
-  return fmt.Sprintf("%s\n%s", strings.Join(headers, ","), results), nil
+  writer.Flush()
+  return results.String(), writer.Error()
 }
@@ -0,0 +1,32 @@
+package main
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+  "go.mongodb.org/mongo-driver/bson"
+)
+
+func TestConvertBsonToCsv(t *testing.T) {
+  data := []bson.D{
+    {{Key: "_id", Value: "1"}, {Key: "tag", Value: 1111}, {Key: "sex", Value: "m"}},
+    {{Key: "_id", Value: "2"}, {Key: "tag", Value: 1112}, {Key: "sex", Value: "f"},
+     {Key: "birth_weight", Value: 32.5}, {Key: "twins", Value: true}},
+    {{Key: "_id", Value: "3"}, {Key: "tag", Value: 1113}, {Key: "sex", Value: "f"}},
+  }
+  csv, err := ConvertBsonToCsv(data)
+  assert.Nil(t, err)
+  assert.Equal(t, "birth_weight,sex,tag,twins\n" +
+                  ",m,1111,\n" +
+                  "32.5,f,1112,true\n" +
+                  ",f,1113,\n", csv)
+}
+
+func TestConvertBsonToCsvQuotes(t *testing.T) {
+  data := []bson.D{
+    {{Key: "tag", Value: 1111}, {Key: "note", Value: "morreu de picada, perto do rio"}},
+  }
+  csv, err := ConvertBsonToCsv(data)
+  assert.Nil(t, err)
+  assert.Equal(t, "note,tag\n" +
+                  "\"morreu de picada, perto do rio\",1111\n", csv, "Fields with commas are quoted")
+}
@@ -8,3 +8,25 @@ db.births.createIndex(
     partialFilterExpression: { tag: { $gt: 0 } }                                                                                  
   }                                                                                                                               
 )  
+
+# Pending conversations are removed once they expire, and there is at
+# most one per phone.
+db.pending_conversations.createIndex(
+  { expires_at: 1 },
+  { expireAfterSeconds: 0 }
+)
+db.pending_conversations.createIndex(
+  { account: 1, phone: 1 },
+  { unique: true }
+)
+
+# Deaths are looked up by animal
+db.deaths.createIndex(
+  { account: 1, tag: 1 }
+)
+
+# There is one animal per births record
+db.animals.createIndex(
+  { account: 1, birth_id: 1 },
+  { unique: true }
+)
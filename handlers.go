@@ -10,8 +10,14 @@ import (
   "context"
   "strconv"
   "strings"
+  "time"
+  "posso-help/internal/account"
+  "posso-help/internal/animal"
   "posso-help/internal/chat"
   "posso-help/internal/db"
+  "posso-help/internal/pedigree"
+  "posso-help/internal/product"
+  "posso-help/internal/scheduler"
   "posso-help/internal/user"
   "github.com/gorilla/mux"
 
@@ -47,6 +53,7 @@ func HandleDownload(w http.ResponseWriter, r *http.Request) {
     return 
   }
 
+  localizeDocuments(data, account.FindLocationByAccount(user.Account))
   csv, err := ConvertBsonToCsv(data) 
   if err != nil {
     w.WriteHeader(http.StatusBadRequest) 
@@ -133,6 +140,7 @@ func HandleUpload(w http.ResponseWriter, r *http.Request) {
         }
       }
     }
+    normalizeRecord(record)
     log.Printf("record: %+v\n", record)
     result, err := collection.InsertOne(context.TODO(), record)
     if err != nil {
@@ -200,6 +208,7 @@ func HandleUploadJSON(w http.ResponseWriter, r *http.Request) {
       }
     }
 
+    normalizeRecord(record)
     log.Printf("record: %+v", record)
     result, err := collection.InsertOne(context.TODO(), record)
     if err != nil {
@@ -254,6 +263,7 @@ func HandleDataGet(w http.ResponseWriter, r *http.Request) {
     fmt.Fprintf(w, "%v", err)
     return
   }
+  localizeRecords(data, account.FindLocationByAccount(user.Account))
 
   json, err := json.Marshal(data)
   if err != nil {
@@ -310,6 +320,7 @@ func HandleDataPut(w http.ResponseWriter, r *http.Request) {
   }
   filter := bson.M{"_id": objID, "account": u.Account}
   delete(data, "_id")
+  normalizeRecord(data)
 
   _, err = collection.UpdateOne(context.TODO(), filter, bson.M{"$set": data})
   if err != nil {
@@ -366,6 +377,7 @@ func HandleDataPatch(w http.ResponseWriter, r *http.Request) {
   }
   filter := bson.M{"_id": objID, "account": u.Account}
   delete(data, "_id")
+  normalizeRecord(data)
 
   _, err = collection.UpdateOne(context.TODO(), filter, bson.M{"$set": data})
   if err != nil {
@@ -438,6 +450,7 @@ func HandleDataPost(w http.ResponseWriter, r *http.Request) {
   }
 
   data["account"] = u.Account
+  normalizeRecord(data)
 
   // Name was used before
   data["created_by"] = u.GetDisplayName()
@@ -454,6 +467,13 @@ func HandleDataPost(w http.ResponseWriter, r *http.Request) {
     return 
   }
 
+  // A sale changes the status of the animal sold
+  if datatype == animal.SalesCollection {
+    if err := animal.Record(u.Account, animal.SaleEvent(data)); err != nil {
+      log.Printf("Error recording sale: %v", err)
+    }
+  }
+
   return 
 }
 
@@ -506,6 +526,361 @@ func HandleDataDelete(w http.ResponseWriter, r *http.Request) {
   return
 }
 
+// HandleWithdrawalsGet returns the treated animals of the account that
+// are still in the withdrawal period and therefore can not be sold yet.
+func HandleWithdrawalsGet(w http.ResponseWriter, r *http.Request) {
+  ctx := r.Context()
+  userID := ctx.Value("user_id")
+  if userID == nil {
+    log.Printf("could not get userid from context")
+    http.Error(w, "Authorization header required", http.StatusUnauthorized)
+    return
+  }
+
+  user, err := user.Read(userID.(string))
+  if err != nil {
+    log.Printf("could not read userID from context")
+    http.Error(w, "User Not Found", http.StatusNotFound)
+    return
+  }
+
+  withdrawals, err := product.FindActiveWithdrawals(user.Account, time.Now())
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+  localizeWithdrawals(withdrawals, account.FindLocationByAccount(user.Account))
+
+  json, err := json.Marshal(withdrawals)
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+  fmt.Fprint(w, string(json))
+}
+
+// HandleTasksGet returns the scheduled tasks of the account
+func HandleTasksGet(w http.ResponseWriter, r *http.Request) {
+  ctx := r.Context()
+  userID := ctx.Value("user_id")
+  if userID == nil {
+    log.Printf("could not get userid from context")
+    http.Error(w, "Authorization header required", http.StatusUnauthorized)
+    return
+  }
+
+  user, err := user.Read(userID.(string))
+  if err != nil {
+    log.Printf("could not read userID from context")
+    http.Error(w, "User Not Found", http.StatusNotFound)
+    return
+  }
+
+  tasks, err := scheduler.FindTasksByAccount(user.Account)
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+  localizeTasks(tasks, account.FindLocationByAccount(user.Account))
+
+  json, err := json.Marshal(tasks)
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+  fmt.Fprint(w, string(json))
+}
+
+// HandleTasksPost schedules a new task, the message is sent to the
+// team phones of the account at due_at.
+func HandleTasksPost(w http.ResponseWriter, r *http.Request) {
+  ctx := r.Context()
+  userID := ctx.Value("user_id")
+  if userID == nil {
+    log.Printf("could not get userid from context")
+    http.Error(w, "Authorization header required", http.StatusUnauthorized)
+    return
+  }
+
+  u, err := user.Read(userID.(string))
+  if err != nil {
+    log.Printf("could not read userID from context")
+    http.Error(w, "User Not Found", http.StatusNotFound)
+    return
+  }
+
+  defer r.Body.Close()
+  request := &scheduler.TaskRequest{}
+  err = json.NewDecoder(r.Body).Decode(request)
+  if err != nil {
+    http.Error(w, "Error unmarshalling JSON", http.StatusBadRequest)
+    log.Printf("Error unmarshalling JSON: %v", err)
+    return
+  }
+
+  if request.Message == "" || request.DueAt.IsZero() {
+    http.Error(w, "message_and_due_at_required", http.StatusBadRequest)
+    return
+  }
+
+  task := request.NewTask(u.Account, u.GetDisplayName())
+  err = scheduler.AddTask(task)
+  if err != nil {
+    http.Error(w, "Error Inserting Data", http.StatusBadRequest)
+    log.Printf("Error Inserting Task: %v", err)
+    return
+  }
+
+  json, err := json.Marshal(task)
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+  fmt.Fprint(w, string(json))
+}
+
+// HandleUnparsedMessagesGet returns the messages of the account that no
+// parser understood, add resolved=true to include the resolved ones.
+func HandleUnparsedMessagesGet(w http.ResponseWriter, r *http.Request) {
+  ctx := r.Context()
+  userID := ctx.Value("user_id")
+  if userID == nil {
+    log.Printf("could not get userid from context")
+    http.Error(w, "Authorization header required", http.StatusUnauthorized)
+    return
+  }
+
+  user, err := user.Read(userID.(string))
+  if err != nil {
+    log.Printf("could not read userID from context")
+    http.Error(w, "User Not Found", http.StatusNotFound)
+    return
+  }
+
+  includeResolved := r.URL.Query().Get("resolved") == "true"
+  messages, err := chat.FindUnparsedMessages(user.Account, includeResolved)
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+
+  json, err := json.Marshal(messages)
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+  fmt.Fprint(w, string(json))
+}
+
+// HandleUnparsedMessageResolve marks an unparsed message as resolved
+func HandleUnparsedMessageResolve(w http.ResponseWriter, r *http.Request) {
+  vars := mux.Vars(r)
+  id := vars["id"]
+
+  ctx := r.Context()
+  userID := ctx.Value("user_id")
+  if userID == nil {
+    log.Printf("could not get userid from context")
+    http.Error(w, "Authorization header required", http.StatusUnauthorized)
+    return
+  }
+
+  u, err := user.Read(userID.(string))
+  if err != nil {
+    log.Printf("could not read userID from context")
+    http.Error(w, "User Not Found", http.StatusNotFound)
+    return
+  }
+
+  objID, err := primitive.ObjectIDFromHex(id)
+  if err != nil {
+    http.Error(w, "invalid_id", http.StatusBadRequest)
+    log.Printf("invalid id: %s %v", id, err)
+    return
+  }
+
+  err = chat.ResolveUnparsedMessage(u.Account, objID, u.GetDisplayName())
+  if err == mongo.ErrNoDocuments {
+    http.Error(w, "record_not_found_or_not_authorized", http.StatusForbidden)
+    return
+  }
+  if err != nil {
+    http.Error(w, "Error Updating Data", http.StatusBadRequest)
+    log.Printf("Error resolving message %s: %v", id, err)
+    return
+  }
+  fmt.Fprint(w, `{"status":"success"}`)
+}
+
+// HandleAnimalsGet returns the current state of the animals of the account
+func HandleAnimalsGet(w http.ResponseWriter, r *http.Request) {
+  ctx := r.Context()
+  userID := ctx.Value("user_id")
+  if userID == nil {
+    log.Printf("could not get userid from context")
+    http.Error(w, "Authorization header required", http.StatusUnauthorized)
+    return
+  }
+
+  user, err := user.Read(userID.(string))
+  if err != nil {
+    log.Printf("could not read userID from context")
+    http.Error(w, "User Not Found", http.StatusNotFound)
+    return
+  }
+
+  animals, err := animal.FindAnimals(user.Account)
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+  localizeAnimals(animals, account.FindLocationByAccount(user.Account))
+
+  json, err := json.Marshal(animals)
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+  fmt.Fprint(w, string(json))
+}
+
+// HandleAnimalsRebuild derives the animals of the account again from
+// the births, deaths and movements collections
+func HandleAnimalsRebuild(w http.ResponseWriter, r *http.Request) {
+  ctx := r.Context()
+  userID := ctx.Value("user_id")
+  if userID == nil {
+    log.Printf("could not get userid from context")
+    http.Error(w, "Authorization header required", http.StatusUnauthorized)
+    return
+  }
+
+  user, err := user.Read(userID.(string))
+  if err != nil {
+    log.Printf("could not read userID from context")
+    http.Error(w, "User Not Found", http.StatusNotFound)
+    return
+  }
+
+  total, err := animal.Rebuild(user.Account)
+  if err != nil {
+    http.Error(w, "Error Rebuilding Animals", http.StatusInternalServerError)
+    log.Printf("Error rebuilding animals of %s: %v", user.Account, err)
+    return
+  }
+  w.Header().Set("Content-Type", "application/json")
+  fmt.Fprintf(w, `{"status":"success","animals":%d}`, total)
+}
+
+// HandlePedigreeGet returns the ancestors and descendants of an animal
+// of the account, up to ?generations=N
+func HandlePedigreeGet(w http.ResponseWriter, r *http.Request) {
+  vars := mux.Vars(r)
+  tag, err := strconv.Atoi(vars["tag"])
+  if err != nil || tag <= 0 {
+    http.Error(w, "invalid_tag", http.StatusBadRequest)
+    return
+  }
+
+  ctx := r.Context()
+  userID := ctx.Value("user_id")
+  if userID == nil {
+    log.Printf("could not get userid from context")
+    http.Error(w, "Authorization header required", http.StatusUnauthorized)
+    return
+  }
+
+  user, err := user.Read(userID.(string))
+  if err != nil {
+    log.Printf("could not read userID from context")
+    http.Error(w, "User Not Found", http.StatusNotFound)
+    return
+  }
+
+  generations := 0
+  if value := r.URL.Query().Get("generations"); value != "" {
+    generations, err = strconv.Atoi(value)
+    if err != nil || generations <= 0 {
+      http.Error(w, "invalid_generations", http.StatusBadRequest)
+      return
+    }
+  }
+
+  tree, err := pedigree.Build(pedigree.NewLookup(user.Account), tag, generations)
+  if err == pedigree.ErrNotFound {
+    http.Error(w, "animal_not_found", http.StatusNotFound)
+    return
+  }
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+
+  json, err := json.Marshal(tree)
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+  fmt.Fprint(w, string(json))
+}
+
+// HandleTimelineGet returns everything recorded about an animal of the
+// account, with the messages that reported it, oldest first
+func HandleTimelineGet(w http.ResponseWriter, r *http.Request) {
+  vars := mux.Vars(r)
+  tag, err := strconv.Atoi(vars["tag"])
+  if err != nil || tag <= 0 {
+    http.Error(w, "invalid_tag", http.StatusBadRequest)
+    return
+  }
+
+  ctx := r.Context()
+  userID := ctx.Value("user_id")
+  if userID == nil {
+    log.Printf("could not get userid from context")
+    http.Error(w, "Authorization header required", http.StatusUnauthorized)
+    return
+  }
+
+  user, err := user.Read(userID.(string))
+  if err != nil {
+    log.Printf("could not read userID from context")
+    http.Error(w, "User Not Found", http.StatusNotFound)
+    return
+  }
+
+  events, err := chat.FindTimeline(user.Account, tag)
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+  if len(events) == 0 {
+    http.Error(w, "animal_not_found", http.StatusNotFound)
+    return
+  }
+  localizeTimeline(events, account.FindLocationByAccount(user.Account))
+
+  json, err := json.Marshal(events)
+  if err != nil {
+    w.WriteHeader(http.StatusBadRequest)
+    fmt.Fprintf(w, "%v", err)
+    return
+  }
+  fmt.Fprint(w, string(json))
+}
+
 func HandleChatMessage(w http.ResponseWriter, r *http.Request) {
   log.Printf("HandleChatMessage")
   defer r.Body.Close()
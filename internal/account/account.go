@@ -5,6 +5,7 @@ import (
   "fmt"
   "context"
   "strings"
+  "time"
   "posso-help/internal/db"
   "go.mongodb.org/mongo-driver/bson"
 )
@@ -14,6 +15,41 @@ type Team struct {
   PhoneNumber  string `bson:"phone_number"`
   Name         string `bson:"name"`
   Language     string `bson:"lang"`
+  // Minimum similarity of misspelled breeds, the default when zero
+  BreedMatchThreshold float64 `bson:"breed_match_threshold"`
+  // IANA time zone of the team, like "America/Sao_Paulo", UTC when empty
+  Timezone     string `bson:"timezone"`
+}
+
+// Location returns the time zone of the team, UTC when it is not set
+// or not valid.
+func (t *Team) Location() *time.Location {
+  return loadLocation(t.Timezone)
+}
+
+// FindLocationByAccount returns the time zone of the account, taken from
+// the first team member with a time zone, UTC when none has one.
+func FindLocationByAccount(account string) *time.Location {
+  teams := db.GetCollection("teams")
+  filter := bson.M{"account": account, "timezone": bson.M{"$nin": []interface{}{nil, ""}}}
+  team := &Team{}
+  err := teams.FindOne(context.TODO(), filter).Decode(team)
+  if err != nil {
+    return time.UTC
+  }
+  return team.Location()
+}
+
+func loadLocation(timezone string) *time.Location {
+  if timezone == "" {
+    return time.UTC
+  }
+  location, err := time.LoadLocation(timezone)
+  if err != nil {
+    log.Printf("Invalid timezone %s: %v", timezone, err)
+    return time.UTC
+  }
+  return location
 }
 
 func getAllPhoneNumberVariants(phoneNumber string) ([]string) {
@@ -59,3 +95,21 @@ func FindAccountByPhoneNumber(phoneNumber string) (*Team, error) {
              phoneNumber, team.Account, err)
   return team, err
 }
+
+// FindTeamsByAccount returns all the team members of the account
+func FindTeamsByAccount(account string) ([]*Team, error) {
+  teams := db.GetCollection("teams")
+  filter := bson.M{"account": account}
+  cursor, err := teams.Find(context.TODO(), filter)
+  if err != nil {
+    log.Printf("Error reading teams for account: %v", account)
+    return nil, err
+  }
+  defer cursor.Close(context.TODO())
+
+  results := []*Team{}
+  if err := cursor.All(context.TODO(), &results); err != nil {
+    return nil, err
+  }
+  return results, nil
+}
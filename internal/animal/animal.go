@@ -0,0 +1,318 @@
+package animal
+
+import (
+  "context"
+  "log"
+  "sort"
+  "time"
+  "posso-help/internal/date"
+  "posso-help/internal/db"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/bson/primitive"
+  "go.mongodb.org/mongo-driver/mongo"
+  "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The animals collection holds the current state of every animal of the
+// herd.  It is derived from the events of the births, deaths, movements
+// and sales collections: parsers record each event as they insert it,
+// and Rebuild derives it again from all the events of an account.
+const AnimalsCollection = "animals"
+
+// Sales of animals, posted to the data API with the tag and the date
+const SalesCollection = "sales"
+
+// Status of an animal
+const ALIVE = "alive"
+const DEAD  = "dead"
+const SOLD  = "sold"
+
+// Kinds of event
+const BORN   = "born"
+const DIED   = "died"
+const MOVED  = "moved"
+const TAGGED = "tagged"
+const SALE   = "sale"
+
+type Animal struct {
+  ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+  Account   string             `bson:"account" json:"account"`
+  // Births record of the animal, untagged calves are only known by it
+  BirthID   primitive.ObjectID `bson:"birth_id" json:"birth_id"`
+  Tag       int                `bson:"tag" json:"tag"`
+  Sex       string             `bson:"sex" json:"sex"`
+  Breed     string             `bson:"breed" json:"breed"`
+  Area      string             `bson:"area" json:"area"`
+  Status    string             `bson:"status" json:"status"`
+  BirthDate string             `bson:"birth_date" json:"birth_date"`
+  Dam       int                `bson:"dam" json:"dam"`
+  Sire      string             `bson:"sire,omitempty" json:"sire,omitempty"`
+  DeathDate string             `bson:"death_date,omitempty" json:"death_date,omitempty"`
+  SaleDate  string             `bson:"sale_date,omitempty" json:"sale_date,omitempty"`
+  UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Event changes the state of an animal, found by BirthID or else by Tag
+type Event struct {
+  Kind    string
+  BirthID primitive.ObjectID
+  Tag     int
+  Date    string
+  Sex     string
+  Breed   string
+  Area    string
+  Dam     int
+  Sire    string
+}
+
+// Apply changes the animal according to the event
+func (a *Animal) Apply(event *Event) {
+  switch event.Kind {
+  case BORN:
+    a.BirthID = event.BirthID
+    a.Tag = event.Tag
+    a.Sex = event.Sex
+    a.Breed = event.Breed
+    a.Area = event.Area
+    a.Dam = event.Dam
+    a.Sire = event.Sire
+    a.BirthDate = event.Date
+    a.Status = ALIVE
+  case DIED:
+    a.Status = DEAD
+    a.DeathDate = event.Date
+  case MOVED:
+    a.Area = event.Area
+  case TAGGED:
+    a.Tag = event.Tag
+  case SALE:
+    a.Status = SOLD
+    a.SaleDate = event.Date
+  }
+}
+
+// Record applies the event to the animal of the account and saves it.
+// Events of animals that are not in the registry are ignored.
+func Record(account string, event *Event) error {
+  animals := db.GetCollection(AnimalsCollection)
+  a := &Animal{Account: account}
+  if event.Kind != BORN {
+    filter := bson.M{"account": account, "birth_id": event.BirthID}
+    if event.BirthID.IsZero() {
+      filter = bson.M{"account": account, "tag": event.Tag}
+    }
+    err := animals.FindOne(context.TODO(), filter).Decode(a)
+    if err == mongo.ErrNoDocuments {
+      log.Printf("animal: %s event of unknown animal %d\n", event.Kind, event.Tag)
+      return nil
+    }
+    if err != nil {
+      return err
+    }
+  }
+
+  a.Apply(event)
+  a.UpdatedAt = time.Now()
+  filter := bson.M{"account": account, "birth_id": a.BirthID}
+  opts := options.Replace().SetUpsert(true)
+  _, err := animals.ReplaceOne(context.TODO(), filter, a, opts)
+  if err != nil {
+    log.Printf("Error saving animal %d: %v", a.Tag, err)
+  }
+  return err
+}
+
+// FindAnimals returns the animals of the account, sorted by tag
+func FindAnimals(account string) ([]*Animal, error) {
+  filter := bson.M{"account": account}
+  opts := options.Find().SetSort(bson.D{{Key: "tag", Value: 1}})
+  cursor, err := db.GetCollection(AnimalsCollection).Find(context.TODO(), filter, opts)
+  if err != nil {
+    return nil, err
+  }
+  animals := []*Animal{}
+  err = cursor.All(context.TODO(), &animals)
+  return animals, err
+}
+
+// Rebuild derives the animals of the account again from all its
+// events, replacing the registry.  It returns the number of animals.
+// Animals are replaced one by one and only then the animals that were
+// not rebuilt are removed, so a failure never leaves the registry empty.
+func Rebuild(account string) (int, error) {
+  events, err := readEvents(account)
+  if err != nil {
+    return 0, err
+  }
+  animals := Replay(events)
+
+  collection := db.GetCollection(AnimalsCollection)
+  writes, births := rebuildWrites(account, animals, time.Now())
+  if len(writes) > 0 {
+    opts := options.BulkWrite().SetOrdered(false)
+    _, err = collection.BulkWrite(context.TODO(), writes, opts)
+    if err != nil {
+      return 0, err
+    }
+  }
+  filter := bson.M{"account": account, "birth_id": bson.M{"$nin": births}}
+  _, err = collection.DeleteMany(context.TODO(), filter)
+  if err != nil {
+    return 0, err
+  }
+  log.Printf("animal: rebuilt %d animals of %s\n", len(animals), account)
+  return len(animals), nil
+}
+
+// rebuildWrites returns the upserts of the animals of the account, by
+// birth_id, and the birth ids that were rebuilt.
+func rebuildWrites(account string, animals []*Animal, now time.Time) ([]mongo.WriteModel, []primitive.ObjectID) {
+  writes := []mongo.WriteModel{}
+  births := []primitive.ObjectID{}
+  for _, a := range animals {
+    a.Account = account
+    a.UpdatedAt = now
+    filter := bson.M{"account": account, "birth_id": a.BirthID}
+    writes = append(writes, mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(a).SetUpsert(true))
+    births = append(births, a.BirthID)
+  }
+  return writes, births
+}
+
+// Replay applies the events, in date order, and returns the animals.
+// Births come first, so an animal exists before anything happens to it.
+func Replay(events []*Event) []*Animal {
+  sort.SliceStable(events, func(i, j int) bool {
+    if (events[i].Kind == BORN) != (events[j].Kind == BORN) {
+      return events[i].Kind == BORN
+    }
+    return date.Before(events[i].Date, events[j].Date)
+  })
+
+  animals := []*Animal{}
+  byBirth := map[primitive.ObjectID]*Animal{}
+  byTag := map[int]*Animal{}
+  for _, event := range events {
+    if event.Kind == BORN {
+      a := &Animal{}
+      a.Apply(event)
+      animals = append(animals, a)
+      byBirth[event.BirthID] = a
+      if a.Tag > 0 {
+        byTag[a.Tag] = a
+      }
+      continue
+    }
+    a, found := byTag[event.Tag]
+    if !event.BirthID.IsZero() {
+      a, found = byBirth[event.BirthID]
+    }
+    if !found {
+      log.Printf("animal: %s event of unknown animal %d\n", event.Kind, event.Tag)
+      continue
+    }
+    a.Apply(event)
+  }
+  return animals
+}
+
+// SaleEvent returns the event of a record of the sales collection
+func SaleEvent(sale map[string]interface{}) *Event {
+  event := &Event{Kind: SALE, Tag: intValue(sale["tag"]), Date: stringValue(sale["date"])}
+  event.BirthID, _ = sale["birth_id"].(primitive.ObjectID)
+  return event
+}
+
+// readEvents returns the events of the account stored in the births,
+// deaths, movements and sales collections.  Births hold the current tag of the
+// animal, so tagging calves needs no event.
+func readEvents(account string) ([]*Event, error) {
+  events := []*Event{}
+  filter := bson.M{"account": account}
+
+  births, err := readAll("births", filter)
+  if err != nil {
+    return nil, err
+  }
+  for _, birth := range births {
+    event := &Event{
+      Kind: BORN,
+      Tag: intValue(birth["tag"]),
+      Date: stringValue(birth["date"]),
+      Sex: stringValue(birth["sex"]),
+      Breed: stringValue(birth["breed"]),
+      Area: stringValue(birth["area"]),
+      Dam: intValue(birth["dam"]),
+      Sire: stringValue(birth["sire"]),
+    }
+    event.BirthID, _ = birth["_id"].(primitive.ObjectID)
+    events = append(events, event)
+    // Deaths reported before the deaths collection only set a cause
+    if _, found := birth["cause"]; found && birth["status"] == nil {
+      events = append(events, &Event{Kind: DIED, BirthID: event.BirthID, Tag: event.Tag})
+    }
+  }
+
+  deaths, err := readAll("deaths", filter)
+  if err != nil {
+    return nil, err
+  }
+  for _, death := range deaths {
+    event := &Event{Kind: DIED, Tag: intValue(death["tag"]), Date: stringValue(death["date"])}
+    event.BirthID, _ = death["birth_id"].(primitive.ObjectID)
+    events = append(events, event)
+  }
+
+  movements, err := readAll("movements", filter)
+  if err != nil {
+    return nil, err
+  }
+  for _, movement := range movements {
+    events = append(events, &Event{
+      Kind: MOVED,
+      Tag: intValue(movement["tag"]),
+      Date: stringValue(movement["date"]),
+      Area: stringValue(movement["to_area"]),
+    })
+  }
+
+  sales, err := readAll(SalesCollection, filter)
+  if err != nil {
+    return nil, err
+  }
+  for _, sale := range sales {
+    events = append(events, SaleEvent(sale))
+  }
+  return events, nil
+}
+
+func readAll(collection string, filter bson.M) ([]bson.M, error) {
+  cursor, err := db.GetCollection(collection).Find(context.TODO(), filter)
+  if err != nil {
+    return nil, err
+  }
+  records := []bson.M{}
+  err = cursor.All(context.TODO(), &records)
+  return records, err
+}
+
+func stringValue(value interface{}) string {
+  text, _ := value.(string)
+  return text
+}
+
+// intValue returns the value of a number field, which can be stored as
+// any of the bson number types.
+func intValue(value interface{}) int {
+  switch number := value.(type) {
+  case int:
+    return number
+  case int32:
+    return int(number)
+  case int64:
+    return int(number)
+  case float64:
+    return int(number)
+  }
+  return 0
+}
@@ -0,0 +1,79 @@
+package animal
+
+import (
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/bson/primitive"
+  "go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestReplay(t *testing.T) {
+  calf := primitive.NewObjectID()
+  events := []*Event{
+    {Kind: MOVED, Tag: 1234, Date: "2025-03-01T00:00:00Z", Area: "sul"},
+    {Kind: DIED, BirthID: calf, Date: "2025-04-01T00:00:00Z"},
+    {Kind: BORN, BirthID: calf, Tag: 0, Dam: 1234, Sex: "f", Breed: "nelore",
+     Area: "norte", Date: "2025-02-10T00:00:00Z"},
+    {Kind: MOVED, Tag: 1234, Date: "2025-02-01T00:00:00Z", Area: "leste"},
+    {Kind: BORN, BirthID: primitive.NewObjectID(), Tag: 1234, Sex: "f", Breed: "angus",
+     Area: "norte", Date: "2022-01-10T00:00:00Z"},
+    {Kind: MOVED, Tag: 9999, Date: "2025-02-01T00:00:00Z", Area: "sul"},
+  }
+  animals := Replay(events)
+  assert.Equal(t, 2, len(animals), "Events of unknown animals are ignored")
+
+  assert.Equal(t, 1234, animals[0].Tag)
+  assert.Equal(t, ALIVE, animals[0].Status)
+  assert.Equal(t, "sul", animals[0].Area, "Movements are applied in date order")
+  assert.Equal(t, "2022-01-10T00:00:00Z", animals[0].BirthDate)
+
+  assert.Equal(t, calf, animals[1].BirthID)
+  assert.Equal(t, 1234, animals[1].Dam)
+  assert.Equal(t, DEAD, animals[1].Status)
+  assert.Equal(t, "2025-04-01T00:00:00Z", animals[1].DeathDate)
+}
+
+func TestReplayDateOffsets(t *testing.T) {
+  events := []*Event{
+    {Kind: BORN, BirthID: primitive.NewObjectID(), Tag: 1234, Date: "2025-01-10T00:00:00Z"},
+    {Kind: MOVED, Tag: 1234, Date: "2025-03-01T01:00:00-03:00", Area: "leste"},
+    {Kind: MOVED, Tag: 1234, Date: "2025-03-01T02:00:00Z", Area: "sul"},
+  }
+  animals := Replay(events)
+  assert.Equal(t, "leste", animals[0].Area, "Dates are compared as times, not as text")
+}
+
+func TestRebuildWrites(t *testing.T) {
+  now := time.Now()
+  first, second := primitive.NewObjectID(), primitive.NewObjectID()
+  animals := []*Animal{{BirthID: first, Tag: 1234}, {BirthID: second}}
+  writes, births := rebuildWrites("acme", animals, now)
+
+  assert.Equal(t, []primitive.ObjectID{first, second}, births)
+  assert.Equal(t, 2, len(writes))
+  write := writes[1].(*mongo.ReplaceOneModel)
+  assert.Equal(t, bson.M{"account": "acme", "birth_id": second}, write.Filter, "Animals are replaced by birth_id")
+  assert.True(t, *write.Upsert, "Animals not in the registry are added")
+  assert.Equal(t, "acme", animals[1].Account)
+  assert.Equal(t, now, animals[1].UpdatedAt)
+}
+
+func TestApplyTagged(t *testing.T) {
+  a := &Animal{}
+  a.Apply(&Event{Kind: BORN, Tag: 0, Dam: 1234, Sex: "m"})
+  a.Apply(&Event{Kind: TAGGED, Tag: 5678})
+  assert.Equal(t, 5678, a.Tag)
+  assert.Equal(t, 1234, a.Dam)
+  assert.Equal(t, ALIVE, a.Status)
+}
+
+func TestApplySale(t *testing.T) {
+  a := &Animal{}
+  a.Apply(&Event{Kind: BORN, Tag: 1234, Sex: "m"})
+  a.Apply(SaleEvent(map[string]interface{}{"tag": float64(1234), "date": "2025-05-02T00:00:00Z"}))
+  assert.Equal(t, SOLD, a.Status)
+  assert.Equal(t, "2025-05-02T00:00:00Z", a.SaleDate)
+}
+
@@ -12,7 +12,8 @@ type Area struct {
   Matches string  `bson:"matches"`
 }
 
-func AddArea(account, name, matches string) error {
+// AddArea inserts a new area and returns the id of the inserted record
+func AddArea(account, name, matches string) (interface{}, error) {
   data := make(map[string]interface{})
   collection := db.GetCollection("areas")
 
@@ -20,12 +21,12 @@ func AddArea(account, name, matches string) error {
   data["name"]    = name
   data["matches"] = matches
 
-  _, err := collection.InsertOne(context.TODO(), data)
+  result, err := collection.InsertOne(context.TODO(), data)
   if err != nil {
     log.Printf("Error inserting new area: %v", err)
-    return err
+    return nil, err
   }
 
   log.Printf("Successfully inserted new Area")
-  return nil
+  return result.InsertedID, nil
 }
@@ -38,15 +38,74 @@ func (ap *AreaParser) LoadAreasByAccount(account string) error {
 	return cursor.Err()
 }
 
+// Areas are matched by similarity when no area name is found in the
+// line, see utils.Similarity.  Matches below the threshold are rejected.
+const MatchThreshold = 0.8
+
+// Names shorter than this are only matched when found in the line, as
+// a single typo makes short names look alike.
+const minFuzzyLength = 4
+
+// ParseAsAreaLine returns the area that best matches the line.  Areas
+// whose names are found in the line are preferred, the longest name
+// first, then the areas whose names are similar to words of the line.
+// Case, accents, punctuation and extra spaces are ignored.
 func (ap *AreaParser) ParseAsAreaLine(line string) (string, bool) {
-  line = utils.SanitizeLine(line)
+  line = utils.NormalizeText(line)
+  if line == "" {
+    return "", false
+  }
+
+  bestName := ""
+  bestScore := 0.0
+  bestLength := 0
   for _, area := range ap.areas {
-    matches := utils.SplitAndTrim(strings.ToLower(area.Matches))
-    if utils.StringContainsOneOf(line, matches) {
-      log.Printf("ParseAsAreaLine: found, name=%+v\n", area.Name)
-      return area.Name, true
+    for _, match := range utils.SplitAndTrim(area.Matches) {
+      match = utils.NormalizeText(match)
+      if match == "" {
+        continue
+      }
+      score := matchScore(line, match)
+      length := len(match)
+      if score > bestScore || (score == bestScore && length > bestLength) {
+        bestName = area.Name
+        bestScore = score
+        bestLength = length
+      }
     }
   }
-  
+
+  if bestScore >= MatchThreshold {
+    log.Printf("ParseAsAreaLine: found, name=%+v score=%.2f\n", bestName, bestScore)
+    return bestName, true
+  }
   return "", false
 }
+
+// matchScore returns 1 when the area name is found in the line, or else
+// the best similarity between the name and the words of the line, taking
+// as many words as the name has.
+func matchScore(line, match string) float64 {
+  if strings.Contains(line, match) {
+    return 1
+  }
+  if len([]rune(match)) < minFuzzyLength {
+    return 0
+  }
+
+  words := strings.Fields(line)
+  size := len(strings.Fields(match))
+  best := 0.0
+  for start := 0; start + size <= len(words); start++ {
+    window := strings.Join(words[start:start+size], " ")
+    if similarity := utils.Similarity(window, match); similarity > best {
+      best = similarity
+    }
+  }
+  return best
+}
+
+// AddArea adds an area to the parser (useful for testing)
+func (ap *AreaParser) AddArea(name, matches string) {
+  ap.areas = append(ap.areas, &Area{Name: name, Matches: matches})
+}
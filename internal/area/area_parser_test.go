@@ -0,0 +1,62 @@
+package area
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func createTestAreaParser() *AreaParser {
+  ap := &AreaParser{}
+  ap.AddArea("Jupiter", "jup;jupiter")
+  ap.AddArea("Jensen Beach", "jensen")
+  ap.AddArea("Espírito Santo", "espírito santo")
+  ap.AddArea("Pasto", "pasto")
+  ap.AddArea("Pasto Norte", "pasto norte")
+  return ap
+}
+
+func TestParseAsAreaLineNormalized(t *testing.T) {
+  ap := createTestAreaParser()
+  tests := []struct {
+    Input string
+    Name  string
+  }{
+    {"Jensen", "Jensen Beach"},
+    {"something before Jup something after", "Jupiter"},
+    {"espirito santo", "Espírito Santo"},
+    {"  ESPÍRITO-SANTO! ", "Espírito Santo"},
+    {"Pasto   Norte", "Pasto Norte"},
+  }
+  for _, test := range tests {
+    name, found := ap.ParseAsAreaLine(test.Input)
+    assert.True(t, found, test.Input)
+    assert.Equal(t, test.Name, name, test.Input)
+  }
+}
+
+func TestParseAsAreaLineBestMatch(t *testing.T) {
+  ap := createTestAreaParser()
+  name, found := ap.ParseAsAreaLine("pasto norte")
+  assert.True(t, found)
+  assert.Equal(t, "Pasto Norte", name, "The longest name found wins over the first one")
+
+  name, found = ap.ParseAsAreaLine("espirito snato")
+  assert.True(t, found)
+  assert.Equal(t, "Espírito Santo", name, "Typos match the closest area")
+
+  name, found = ap.ParseAsAreaLine("area jupitr")
+  assert.True(t, found)
+  assert.Equal(t, "Jupiter", name, "Typos match words of the line")
+}
+
+func TestParseAsAreaLineNotFound(t *testing.T) {
+  ap := createTestAreaParser()
+  _, found := ap.ParseAsAreaLine("fazenda sul")
+  assert.False(t, found)
+
+  _, found = ap.ParseAsAreaLine("jum")
+  assert.False(t, found, "Short names need to be found in the line")
+
+  _, found = ap.ParseAsAreaLine("   ")
+  assert.False(t, found)
+}
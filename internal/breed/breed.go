@@ -16,8 +16,14 @@ type Breed struct {
 	Matches string `bson:"matches"`
 }
 
+// Breeds are matched by similarity when the text does not match exactly,
+// see utils.Similarity.  Matches below the threshold are rejected.
+const DefaultMatchThreshold = 0.75
+
 type BreedParser struct {
 	breeds []*Breed
+	// Minimum similarity of a fuzzy match, DefaultMatchThreshold when zero
+	Threshold float64
 }
 
 // LoadBreedsByAccount loads breeds for the given account plus global breeds
@@ -51,15 +57,44 @@ func (bp *BreedParser) LoadBreedsByAccount(account string) error {
 // MatchBreed checks if the given text matches any breed's matches
 // Returns the breed name if found, empty string otherwise
 func (bp *BreedParser) MatchBreed(text string) (string, bool) {
+	name, _, found := bp.MatchBreedWithConfidence(text)
+	return name, found
+}
+
+// MatchBreedWithConfidence returns the breed whose matches are the
+// closest to the text, ignoring case and accents, and the confidence of
+// the match: 1 for an exact match, less for misspellings like "nelroe".
+// A breed is only found when the confidence reaches the threshold.
+func (bp *BreedParser) MatchBreedWithConfidence(text string) (string, float64, bool) {
 	text = strings.ToLower(strings.TrimSpace(text))
+	bestName := ""
+	bestConfidence := 0.0
 	for _, breed := range bp.breeds {
 		matches := utils.SplitAndTrim(strings.ToLower(breed.Matches))
 		if utils.StringIsOneOf(text, matches) {
 			log.Printf("MatchBreed: found, name=%s for text=%s", breed.Name, text)
-			return breed.Name, true
+			return breed.Name, 1, true
 		}
+		for _, match := range matches {
+			if confidence := utils.Similarity(text, match); confidence > bestConfidence {
+				bestName = breed.Name
+				bestConfidence = confidence
+			}
+		}
+	}
+
+	if bestConfidence >= bp.threshold() {
+		log.Printf("MatchBreed: found, name=%s for text=%s confidence=%.2f", bestName, text, bestConfidence)
+		return bestName, bestConfidence, true
+	}
+	return "", bestConfidence, false
+}
+
+func (bp *BreedParser) threshold() float64 {
+	if bp.Threshold > 0 {
+		return bp.Threshold
 	}
-	return "", false
+	return DefaultMatchThreshold
 }
 
 // IsValidBreed checks if the given breed name matches any loaded breed
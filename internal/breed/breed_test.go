@@ -0,0 +1,52 @@
+package breed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestBreedParser() *BreedParser {
+	bp := &BreedParser{}
+	bp.AddBreed("angus", "angus")
+	bp.AddBreed("nelore", "nelore;nel")
+	bp.AddBreed("mediterrâneo", "mediterrâneo")
+	return bp
+}
+
+func TestMatchBreedExact(t *testing.T) {
+	bp := createTestBreedParser()
+	name, confidence, found := bp.MatchBreedWithConfidence(" Nel ")
+	assert.True(t, found)
+	assert.Equal(t, "nelore", name)
+	assert.Equal(t, 1.0, confidence)
+}
+
+func TestMatchBreedFuzzy(t *testing.T) {
+	bp := createTestBreedParser()
+	tests := []struct {
+		Input string
+		Name  string
+	}{
+		{"nelroe", "nelore"},
+		{"angos", "angus"},
+		{"mediterraneo", "mediterrâneo"},
+	}
+	for _, test := range tests {
+		name, confidence, found := bp.MatchBreedWithConfidence(test.Input)
+		assert.True(t, found, test.Input)
+		assert.Equal(t, test.Name, name, test.Input)
+		assert.Greater(t, confidence, 0.75, test.Input)
+	}
+
+	_, _, found := bp.MatchBreedWithConfidence("brahman")
+	assert.False(t, found, "Breeds that are not close are not matched")
+}
+
+func TestMatchBreedThreshold(t *testing.T) {
+	bp := createTestBreedParser()
+	bp.Threshold = 0.9
+	_, confidence, found := bp.MatchBreedWithConfidence("angos")
+	assert.False(t, found, "Below the threshold")
+	assert.InDelta(t, 0.8, confidence, 0.001)
+}
@@ -0,0 +1,124 @@
+package cause
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"posso-help/internal/db"
+	"posso-help/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Death causes of the account, the global causes belong to the all
+// zeros account.
+type Cause struct {
+	Name    string `bson:"name"`
+	Matches string `bson:"matches"`
+}
+
+type CauseParser struct {
+	causes []*Cause
+}
+
+// LoadCausesByAccount loads death causes for the given account plus global causes
+func (cp *CauseParser) LoadCausesByAccount(account string) error {
+	collection := db.GetCollection("death_causes")
+
+	// Include both account-specific causes and global causes (all zeros account)
+	accounts := []string{account, "000000000000000000000000"}
+	filter := bson.M{"account": bson.M{"$in": accounts}}
+
+	cursor, err := collection.Find(context.TODO(), filter)
+	if err != nil {
+		log.Printf("Error reading death causes for account: %v", account)
+		return err
+	}
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		cause := &Cause{}
+		if err := cursor.Decode(cause); err != nil {
+			log.Printf("Error decoding death cause document: %v", err)
+			continue
+		}
+		log.Printf("LoadCausesByAccount(%s): %s  %s", account, cause.Name, cause.Matches)
+		cp.causes = append(cp.causes, cause)
+	}
+
+	return cursor.Err()
+}
+
+// MatchCause finds the cause at the start of the text, ignoring case,
+// accents and punctuation.  Causes can have several words, like
+// "picada de cobra", and the longest match wins.  The words after the
+// cause are returned as the note, "raio perto da cerca" returns the
+// cause "raio" and the note "perto da cerca".
+func (cp *CauseParser) MatchCause(text string) (string, string, bool) {
+	fields := strings.Fields(text)
+	// Normalized words, and the field each of them comes from
+	words := []string{}
+	fieldOf := []int{}
+	for index, field := range fields {
+		for _, word := range strings.Fields(utils.NormalizeText(field)) {
+			words = append(words, word)
+			fieldOf = append(fieldOf, index)
+		}
+	}
+
+	bestName := ""
+	bestLength := 0
+	for _, cause := range cp.causes {
+		for _, match := range utils.SplitAndTrim(cause.Matches) {
+			matchWords := strings.Fields(utils.NormalizeText(match))
+			if len(matchWords) <= bestLength || !hasPrefix(words, matchWords) {
+				continue
+			}
+			// The cause must end with a whole field, "morto" is not the
+			// start of "morto-vivo"
+			end := len(matchWords)
+			if end < len(words) && fieldOf[end] == fieldOf[end-1] {
+				continue
+			}
+			bestName = cause.Name
+			bestLength = end
+		}
+	}
+
+	if bestLength == 0 {
+		return "", "", false
+	}
+	note := ""
+	if bestLength < len(words) {
+		note = strings.Join(fields[fieldOf[bestLength]:], " ")
+	}
+	log.Printf("MatchCause: found, name=%s note=%s for text=%s", bestName, note, text)
+	return bestName, note, true
+}
+
+func hasPrefix(words, prefix []string) bool {
+	if len(prefix) == 0 || len(prefix) > len(words) {
+		return false
+	}
+	for index, word := range prefix {
+		if words[index] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// GetCauseNames returns a slice of all loaded cause names
+func (cp *CauseParser) GetCauseNames() []string {
+	names := make([]string, len(cp.causes))
+	for i, cause := range cp.causes {
+		names[i] = cause.Name
+	}
+	return names
+}
+
+// AddCause adds a cause to the parser (useful for testing)
+func (cp *CauseParser) AddCause(name, matches string) {
+	cp.causes = append(cp.causes, &Cause{Name: name, Matches: matches})
+}
@@ -0,0 +1,47 @@
+package cause
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestCauseParser() *CauseParser {
+	cp := &CauseParser{}
+	cp.AddCause("morto", "morto;morta")
+	cp.AddCause("nasceu morto", "nasceu morto;nasceu-morto")
+	cp.AddCause("picada de cobra", "picada de cobra;cobra")
+	cp.AddCause("tristeza parasitária", "tristeza parasitária;tristeza")
+	return cp
+}
+
+func TestMatchCause(t *testing.T) {
+	cp := createTestCauseParser()
+	tests := []struct {
+		Input string
+		Name  string
+		Note  string
+	}{
+		{"morto", "morto", ""},
+		{"Morta", "morto", ""},
+		{"nasceu morto", "nasceu morto", ""},
+		{"nasceu-morto", "nasceu morto", ""},
+		{"picada de cobra perto do açude", "picada de cobra", "perto do açude"},
+		{"cobra", "picada de cobra", ""},
+		{"Tristeza Parasitaria, 3 dias doente", "tristeza parasitária", "3 dias doente"},
+	}
+	for _, test := range tests {
+		name, note, found := cp.MatchCause(test.Input)
+		assert.True(t, found, test.Input)
+		assert.Equal(t, test.Name, name, test.Input)
+		assert.Equal(t, test.Note, note, test.Input)
+	}
+}
+
+func TestMatchCauseNotFound(t *testing.T) {
+	cp := createTestCauseParser()
+	for _, input := range []string{"", "raio", "nasceu", "morto-vivo", "picada de abelha"} {
+		_, _, found := cp.MatchCause(input)
+		assert.False(t, found, input)
+	}
+}
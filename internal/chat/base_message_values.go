@@ -3,10 +3,13 @@ package chat
 import "go.mongodb.org/mongo-driver/bson"
 
 type BaseMessageValues struct {
-  Account     string `json:"account"`
-  PhoneNumber string `json:"phone"`
-  Name        string `json:"name"`
-  Date        string `json:"date"`
+  Account     string       `json:"account"`
+  PhoneNumber string       `json:"phone"`
+  Name        string       `json:"name"`
+  Date        string       `json:"date"`
+  MessageId   string       `json:"message_id"`
+  Changes     []*Change    `json:"-"`
+  LineErrors  []*LineError `json:"-"`
 }
 
 func (bmv *BaseMessageValues) ToMap() bson.D {
@@ -17,3 +20,18 @@ func (bmv *BaseMessageValues) ToMap() bson.D {
     {"date", bmv.Date},
   }
 }
+
+// ToMapWithDate returns the same document as ToMap, but with the message
+// date replaced by the given date when one was parsed from the message.
+func (bmv *BaseMessageValues) ToMapWithDate(date string) bson.D {
+  document := bmv.ToMap()
+  if date == "" {
+    return document
+  }
+  for index, element := range document {
+    if element.Key == "date" {
+      document[index].Value = date
+    }
+  }
+  return document
+}
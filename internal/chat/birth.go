@@ -1,14 +1,21 @@
 package chat
 
 import (
-  "context"
   "log"
   "fmt"
+  "strconv"
   "strings"
+  "time"
+  "context"
+  "posso-help/internal/animal"
   "posso-help/internal/area"
   "posso-help/internal/breed"
+  "posso-help/internal/chat/calvingtag"
+  "posso-help/internal/chat/line"
+  "posso-help/internal/chat/twintag"
+  "posso-help/internal/chat/weighttag"
+  "posso-help/internal/conversation"
   "posso-help/internal/db"
-  "posso-help/internal/date"
   "posso-help/internal/utils"
   "go.mongodb.org/mongo-driver/bson"
   "go.mongodb.org/mongo-driver/mongo"
@@ -31,16 +38,62 @@ type BirthEntry struct {
   Dam      int    `json:"dam"`
   Sex      string `json:"sex"`
   Breed    string `json:"breed"`
+  Sire     string `json:"sire"`
+  // Birth weight in kg, zero when not given
+  Weight   float64 `json:"birth_weight"`
+  Twins    bool   `json:"twins"`
+  // Calving ease score from 1 to 5, zero when not given
+  CalvingEase int `json:"calving_ease"`
+  // Index and text of the line of the message
+  line     int
+  text     string
+}
+
+// Keywords followed by the dam or the sire on a birth line,
+// "1111 m angus mãe 5678 pai touro1"
+var DAM_KEYWORDS = []string{"mãe", "mae", "dam", "mother"}
+var FATHER_KEYWORDS = []string{"pai", "father"}
+
+// Reasons the dam of a birth line was not linked
+const DAM_NOT_FOUND = "dam_not_found"
+const DAM_NOT_FEMALE = "dam_not_female"
+
+// DamError is a dam given on a birth line that is not a cow of the herd,
+// the birth is saved without the dam.
+type DamError struct {
+  Tag    int
+  Dam    int
+  Reason string
+}
+
+// BreedGuess is a misspelled breed interpreted as the closest breed
+type BreedGuess struct {
+  Text       string
+  Breed      string
+  Confidence float64
 }
 
 type BirthMessage struct {
+  LineErrors
+  LineClaims
+  // Time the message was received, dates are relative to it
+  Received time.Time
   Date string
   Entries []*BirthEntry
   Area *area.Area
   AreaParser *area.AreaParser
   BreedParser *breed.BreedParser
   NewAreaFound bool
+  BreedGuesses []*BreedGuess
+  DamErrors []*DamError
   Total int
+  // Births not saved because their tag is already in the herd
+  Duplicates int
+}
+
+// SetDate sets the date of the message, shared by the records
+func (b *BirthMessage) SetDate(date string) {
+  b.Date = date
 }
 
 func (b *BirthMessage) GetCollection() string {
@@ -52,21 +105,25 @@ func (b *BirthMessage) Parse(message string) bool {
   lines := strings.Split(message, "\n")
   parsedLines := map[int]bool{}
   for index, line := range lines {
-    if date, found := date.ParseAsDateLine(line); found {
+    if date, found := b.parseDateLine(index, line, b.Received); found {
       b.Date = date
       parsedLines[index] = true
     }
     if entry := b.parseAsBirthLine(line); entry != nil {
+      entry.line, entry.text = index, line
       b.Entries = append(b.Entries, entry)
       b.Total++
       found = true
       parsedLines[index] = true
+      b.ClaimLine(index)
     }
     if entry := b.parseAsCalfLine(line); entry != nil {
+      entry.line, entry.text = index, line
       b.Entries = append(b.Entries, entry)
       b.Total++
       found = true
       parsedLines[index] = true
+      b.ClaimLine(index)
     }
     if b.AreaParser != nil {
       if areaName, found := b.AreaParser.ParseAsAreaLine(line); found {
@@ -74,14 +131,21 @@ func (b *BirthMessage) Parse(message string) bool {
         parsedLines[index] = true
       }
     }
+    if !parsedLines[index] {
+      b.diagnoseBirthLine(index, line)
+    }
   }
 
-
   // If we found at least one birth, and there was no "known area"
   // and the last line was not parsed, maybe the last line is a 
-  // new area.
-  if found && b.Area == nil && !parsedLines[len(lines)-1] {
-    newArea := utils.SanitizeLine(lines[len(lines)-1])
+  // new area.  Blank lines, or lines claimed by other parsers of the
+  // message, are skipped.
+  last := len(lines)-1
+  for last > 0 && utils.SanitizeLine(lines[last]) == "" {
+    last--
+  }
+  if found && b.Area == nil && !parsedLines[last] && !b.HasLineError(last) {
+    newArea := utils.SanitizeLine(lines[last])
     b.Area = &area.Area{Name:newArea}
     log.Printf("New Area Found \"%s\"", newArea)
     b.NewAreaFound = true
@@ -94,19 +158,42 @@ func (b *BirthMessage) Parse(message string) bool {
   return found 
 }
 
+// Confirmation asks the sender to confirm a new area before the births
+// are saved, so typos do not create areas.
+func (b *BirthMessage) Confirmation() (string, string, bool) {
+  if !b.NewAreaFound {
+    return "", "", false
+  }
+  return conversation.NEW_AREA, b.Area.Name, true
+}
+
+// Decline saves the births without area when the new area was not
+// confirmed
+func (b *BirthMessage) Decline(value string) {
+  if b.NewAreaFound && b.Area.Name == value {
+    b.Area = &area.Area{Name: "unknown"}
+    b.NewAreaFound = false
+  }
+}
+
 func (b *BirthMessage) parseAsBirthLine(line string) (*BirthEntry) {
   var num int
   var sex, breedText string
+  original := line
   line = utils.SanitizeLine(line)
 
   // Standard Birth Line
   n, err := fmt.Sscanf(line, "%d %s %s", &num, &sex, &breedText)
   if err == nil && n == 3 && num > 0 && utils.StringIsOneOf(sex, SEXES) {
     // Check breed against account-specific breeds if parser is available
-    // MatchBreed returns the canonical breed name if a match is found
+    // matchBreed returns the canonical breed name if a match is found
     if b.BreedParser != nil {
-      if breedName, found := b.BreedParser.MatchBreed(breedText); found {
-        return &BirthEntry{num, 0, sex, breedName}
+      if breedName, found := b.matchBreed(breedText); found {
+        entry := &BirthEntry{Id: num, Sex: sex, Breed: breedName}
+        // The sire keeps its case, "pai TOURO1"
+        parseParents(entry, lastFields(original, len(strings.Fields(line)) - 3))
+        parseDetails(entry, strings.Join(strings.Fields(line)[3:], " "))
+        return entry
       }
     }
   }
@@ -114,6 +201,106 @@ func (b *BirthMessage) parseAsBirthLine(line string) (*BirthEntry) {
   return nil
 }
 
+// parseParents sets the dam and sire given after the breed, unknown
+// words are ignored.
+func parseParents(entry *BirthEntry, words []string) {
+  for index := 0; index+1 < len(words); index++ {
+    keyword := strings.ToLower(words[index])
+    switch {
+    case utils.StringIsOneOf(keyword, DAM_KEYWORDS):
+      if dam, err := strconv.Atoi(words[index+1]); err == nil && dam > 0 {
+        entry.Dam = dam
+        index++
+      }
+    case utils.StringIsOneOf(keyword, FATHER_KEYWORDS),
+         utils.StringIsOneOf(keyword, SIRE_KEYWORDS):
+      entry.Sire = words[index+1]
+      index++
+    }
+  }
+}
+
+// lastFields returns the last count words of the text
+func lastFields(text string, count int) []string {
+  words := strings.Fields(text)
+  if count <= 0 || count > len(words) {
+    return nil
+  }
+  return words[len(words)-count:]
+}
+
+// parseDetails sets the birth weight, twins and calving ease given after
+// the breed, "32kg gemeos parto 2".
+func parseDetails(entry *BirthEntry, text string) {
+  parser := line.NewLineParser().
+    CanHave("weight", weighttag.New()).
+    CanHave("twins", twintag.New()).
+    CanHave("calving", calvingtag.New())
+  parser.Parse(text)
+
+  if weight := parser.Value("weight"); weight != "" {
+    entry.Weight, _ = strconv.ParseFloat(strings.Replace(weight, ",", ".", 1), 64)
+  }
+  entry.Twins = parser.Value("twins") != ""
+  entry.CalvingEase = parser.ValueAsInt("calving")
+}
+
+// matchBreed matches the breed, and keeps the misspelled breeds that
+// were interpreted as the closest breed so the reply can tell the team.
+func (b *BirthMessage) matchBreed(text string) (string, bool) {
+  name, confidence, found := b.BreedParser.MatchBreedWithConfidence(text)
+  if !found || confidence >= 1 {
+    return name, found
+  }
+  for _, guess := range b.BreedGuesses {
+    if guess.Text == text {
+      return name, found
+    }
+  }
+  b.BreedGuesses = append(b.BreedGuesses, &BreedGuess{text, name, confidence})
+  return name, found
+}
+
+// diagnoseBirthLine adds a line error when the line looks like a birth
+// or calf line, "{tag} {sex} {breed}", but its sex or breed is not known.
+func (b *BirthMessage) diagnoseBirthLine(index int, line string) {
+  words := strings.Fields(utils.SanitizeLine(line))
+  if len(words) > 0 && utils.StringIsOneOf(words[0], CALF_KEYWORDS) {
+    words = words[1:]
+  }
+  if len(words) < 2 {
+    return
+  }
+  if tag, err := strconv.Atoi(words[0]); err != nil || tag <= 0 {
+    return
+  }
+
+  if !utils.StringIsOneOf(words[1], SEXES) {
+    // Only lines with a short sex or a known breed are births, other
+    // lines can be deaths or pregnancy checks.
+    if len([]rune(words[1])) <= 2 || b.isBreed(words, 2) {
+      b.AddLineError(index, line, INVALID_SEX)
+    }
+    return
+  }
+  if len(words) < 3 || b.BreedParser == nil {
+    b.AddLineError(index, line, UNKNOWN_BREED)
+    return
+  }
+  if _, found := b.BreedParser.MatchBreed(words[2]); !found {
+    b.AddLineError(index, line, UNKNOWN_BREED)
+  }
+}
+
+// isBreed reports whether the word at the index is a known breed
+func (b *BirthMessage) isBreed(words []string, index int) bool {
+  if index >= len(words) || b.BreedParser == nil {
+    return false
+  }
+  _, found := b.BreedParser.MatchBreed(words[index])
+  return found
+}
+
 // Keywords that indicate a calf entry (English and Portuguese)
 var CALF_KEYWORDS = []string{"calf", "bezerro", "bezerra", "bez"}
 
@@ -129,8 +316,10 @@ func (b *BirthMessage) parseAsCalfLine(line string) (*BirthEntry) {
     if err == nil && n == 4 && keywordMatch == keyword && dam > 0 && utils.StringIsOneOf(sex, SEXES) {
       // Check breed against account-specific breeds if parser is available
       if b.BreedParser != nil {
-        if breedName, found := b.BreedParser.MatchBreed(breedText); found {
-          return &BirthEntry{0, dam, sex, breedName}
+        if breedName, found := b.matchBreed(breedText); found {
+          entry := &BirthEntry{Dam: dam, Sex: sex, Breed: breedName}
+          parseDetails(entry, strings.Join(strings.Fields(line)[4:], " "))
+          return entry
         }
       }
     }
@@ -147,64 +336,160 @@ func (b *BirthMessage) Text(lang string) string {
               "Adicionamos %d nascimentos à área %s.",
   }
 
-  if lang == "pt-BR" ||  lang == "en-US" {
-    return fmt.Sprintf(reply[lang], b.Total, b.Area.Name)
+  guess := map[string]string {
+    "en-US" : "\nInterpreted '%s' as %s.",
+    "pt-BR" : "\nInterpretamos '%s' como %s.",
+  }
+  damErrors := map[string]map[string]string {
+    DAM_NOT_FOUND : {
+      "en-US" : "\nDam %d of %d is not in the herd, saved without dam.",
+      "pt-BR" : "\nMãe %d de %d não está no rebanho, salvo sem mãe.",
+    },
+    DAM_NOT_FEMALE : {
+      "en-US" : "\nDam %d of %d is not female, saved without dam.",
+      "pt-BR" : "\nMãe %d de %d não é fêmea, salvo sem mãe.",
+    },
   }
 
-  log.Printf("Unsupported or Unknown Language: (%s)", lang)
-  return fmt.Sprintf(reply["pt-BR"], b.Total, b.Area.Name)
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+
+  text := fmt.Sprintf(reply[lang], b.Total - b.Duplicates, b.Area.Name)
+  for _, breedGuess := range b.BreedGuesses {
+    text += fmt.Sprintf(guess[lang], breedGuess.Text, breedGuess.Breed)
+  }
+  for _, damError := range b.DamErrors {
+    text += fmt.Sprintf(damErrors[damError.Reason][lang], damError.Dam, damError.Tag)
+  }
+  return text
 }
 
 func (b *BirthMessage) insertBirth(bmv *BaseMessageValues, birth *BirthEntry) error {
-  births := db.GetCollection("births")
   document := bmv.ToMap()
   document = append(document, bson.E{Key: "tag", Value: birth.Id})
   document = append(document, bson.E{Key: "dam", Value: birth.Dam})
   document = append(document, bson.E{Key: "sex", Value: birth.Sex})
   document = append(document, bson.E{Key: "breed", Value: birth.Breed})
   document = append(document, bson.E{Key: "area", Value: b.Area.Name})
+  if birth.Sire != "" {
+    document = append(document, bson.E{Key: "sire", Value: birth.Sire})
+  }
+  document = appendDetails(document, birth)
   if b.Date != "" {
     document = append(document, bson.E{Key: "date", Value: b.Date})
   }
-  _, err := births.InsertOne(context.TODO(), document)
-  return err
+  err := insertRecord(bmv, "births", document)
+  if err != nil {
+    return err
+  }
+  b.recordBirth(bmv, birth.Id, birth.Dam, birth)
+  return nil
+}
+
+// checkDam returns why the dam can not be the mother of a birth, or an
+// empty reason when it is a cow of the herd.
+func checkDam(account string, dam int) (string, error) {
+  filter := bson.M{"account": account, "tag": dam}
+  animal := bson.M{}
+  err := db.GetCollection("births").FindOne(context.TODO(), filter).Decode(&animal)
+  if err == mongo.ErrNoDocuments {
+    return DAM_NOT_FOUND, nil
+  }
+  if err != nil {
+    return "", err
+  }
+  if animal["sex"] != FEMALE {
+    return DAM_NOT_FEMALE, nil
+  }
+  return "", nil
 }
 
 func (b *BirthMessage) insertCalf(bmv *BaseMessageValues, birth *BirthEntry) error {
   log.Printf("Duplicate tag %d found, converting to calf entry with dam=%d", birth.Id, birth.Id)
-  births := db.GetCollection("births")
   document := bmv.ToMap()
   document = append(document, bson.E{Key: "tag", Value: 0})
   document = append(document, bson.E{Key: "dam", Value: birth.Id})
   document = append(document, bson.E{Key: "sex", Value: birth.Sex})
   document = append(document, bson.E{Key: "breed", Value: birth.Breed})
   document = append(document, bson.E{Key: "area", Value: b.Area.Name})
+  document = appendDetails(document, birth)
   if b.Date != "" {
     document = append(document, bson.E{Key: "date", Value: b.Date})
   }
-  _, err := births.InsertOne(context.TODO(), document)
-  return err
+  err := insertRecord(bmv, "births", document)
+  if err != nil {
+    return err
+  }
+  b.recordBirth(bmv, 0, birth.Id, birth)
+  return nil
+}
+
+// recordBirth adds the animal born to the animals registry
+func (b *BirthMessage) recordBirth(bmv *BaseMessageValues, tag, dam int, birth *BirthEntry) {
+  date := b.Date
+  if date == "" {
+    date = bmv.Date
+  }
+  recordAnimal(bmv.Account, &animal.Event{
+    Kind: animal.BORN,
+    BirthID: lastInsertedID(bmv),
+    Tag: tag,
+    Date: date,
+    Sex: birth.Sex,
+    Breed: birth.Breed,
+    Area: b.Area.Name,
+    Dam: dam,
+    Sire: birth.Sire,
+  })
+}
+
+// appendDetails adds the birth details that were given to the document
+func appendDetails(document bson.D, birth *BirthEntry) bson.D {
+  if birth.Weight > 0 {
+    document = append(document, bson.E{Key: "birth_weight", Value: birth.Weight})
+  }
+  if birth.Twins {
+    document = append(document, bson.E{Key: "twins", Value: true})
+  }
+  if birth.CalvingEase > 0 {
+    document = append(document, bson.E{Key: "calving_ease", Value: birth.CalvingEase})
+  }
+  return document
 }
 
 func (b *BirthMessage) Insert(bmv *BaseMessageValues) error {
+  b.DamErrors = nil
+  b.Duplicates = 0
   for _, birth := range b.Entries {
-    err := b.insertBirth(bmv, birth)
-    if err != nil {
-      if mongo.IsDuplicateKeyError(err) && birth.Id > 0 {
-        err = b.insertCalf(bmv, birth)
-        if err != nil {
-          return err
-        }
-      } else {
+    parents := birth.Dam > 0 || birth.Sire != ""
+    // Calf lines are saved even when their dam is not registered
+    if birth.Id > 0 && birth.Dam > 0 {
+      reason, err := checkDam(bmv.Account, birth.Dam)
+      if err != nil {
         return err
       }
+      if reason != "" {
+        log.Printf("birth %d with invalid dam %d: %s", birth.Id, birth.Dam, reason)
+        b.DamErrors = append(b.DamErrors, &DamError{birth.Id, birth.Dam, reason})
+        birth.Dam = 0
+      }
+    }
+    err := b.insertBirth(bmv, birth)
+    if mongo.IsDuplicateKeyError(err) && birth.Id > 0 {
+      // A tag already in the herd is taken as the dam of an untagged
+      // calf, unless the line gave the parents of the birth.
+      if parents {
+        log.Printf("Duplicate tag %d on a birth line with parents, not saved", birth.Id)
+        b.AddLineError(birth.line, birth.text, DUPLICATE_TAG)
+        b.Duplicates++
+        continue
+      }
+      err = b.insertCalf(bmv, birth)
     }
-  }
-
-  if b.NewAreaFound {
-    err := area.AddArea(bmv.Account, b.Area.Name, b.Area.Name)
     if err != nil {
-      fmt.Printf("Could not add new area %v", err)
+      return err
     }
   }
 
@@ -3,6 +3,7 @@ package chat
 import (
   "testing"
   "posso-help/internal/breed"
+  "posso-help/internal/conversation"
   "github.com/stretchr/testify/assert"
 )
 
@@ -205,3 +206,121 @@ func TestParseBithLine(t *testing.T) {
   }
   */
 }
+
+func TestBirthMessageLineErrors(t *testing.T) {
+  input := `31/02
+            1234 m nelore
+            1235 x nelore
+            bez 1236 f brahman
+            1237 f angus`
+
+  bm := &BirthMessage{BreedParser: createTestBreedParser()}
+  found := bm.Parse(input)
+
+  assert.True(t, found, "Should find births")
+  assert.Equal(t, 2, bm.Total, "Total births do not match")
+  assert.Equal(t, "unknown", bm.Area.Name, "Line errors are not a new area")
+
+  errors := bm.GetLineErrors()
+  assert.Equal(t, 3, len(errors), "Wrong number of line errors")
+  assert.Equal(t, &LineError{1, "31/02", BAD_DATE}, errors[0])
+  assert.Equal(t, &LineError{3, "1235 x nelore", INVALID_SEX}, errors[1])
+  assert.Equal(t, &LineError{4, "bez 1236 f brahman", UNKNOWN_BREED}, errors[2])
+
+  text := LineErrorsText("en-US", errors)
+  assert.Contains(t, text, "Lines not understood:")
+  assert.Contains(t, text, "3: \"1235 x nelore\" (invalid sex)")
+}
+
+func TestBirthMessageLastLineError(t *testing.T) {
+  input := `1234 m nelore
+            1235 m brahman`
+
+  bm := &BirthMessage{BreedParser: createTestBreedParser()}
+  bm.Parse(input)
+
+  assert.Equal(t, 1, bm.Total, "Total births do not match")
+  assert.Equal(t, "unknown", bm.Area.Name, "A misspelled birth is not a new area")
+  assert.Equal(t, 1, len(bm.GetLineErrors()), "Wrong number of line errors")
+}
+
+func TestBirthMessageConfirmation(t *testing.T) {
+  bm := &BirthMessage{BreedParser: createTestBreedParser()}
+  bm.Parse("88888 m cruzado\npasto nrte")
+  kind, value, needed := bm.Confirmation()
+  assert.True(t, needed, "A new area needs confirmation")
+  assert.Equal(t, conversation.NEW_AREA, kind)
+  assert.Equal(t, "pasto nrte", value)
+
+  bm.Decline("pasto nrte")
+  _, _, needed = bm.Confirmation()
+  assert.False(t, needed, "A declined area is not asked again")
+  assert.Equal(t, "unknown", bm.Area.Name, "Births of a declined area have no area")
+
+  bm = &BirthMessage{BreedParser: createTestBreedParser()}
+  bm.Parse("88888 m cruzado")
+  _, _, needed = bm.Confirmation()
+  assert.False(t, needed, "No confirmation without a new area")
+}
+
+func TestBirthMessageBreedGuess(t *testing.T) {
+  input := `1234 m nelroe
+            1235 f nelroe
+            bez 1236 f angos`
+
+  bm := &BirthMessage{BreedParser: createTestBreedParser()}
+  bm.Parse(input)
+
+  assert.Equal(t, 3, bm.Total, "Misspelled breeds are interpreted")
+  assert.Equal(t, "nelore", bm.Entries[0].Breed)
+  assert.Equal(t, "angus", bm.Entries[2].Breed)
+  assert.Equal(t, 2, len(bm.BreedGuesses), "Guesses are listed once")
+  assert.Equal(t, "Zap Manejo has detected birth data. We added 3 births to area unknown." +
+                  "\nInterpreted 'nelroe' as nelore.\nInterpreted 'angos' as angus.", bm.Text("en-US"))
+}
+
+func TestBirthLineParents(t *testing.T) {
+  bm := &BirthMessage{BreedParser: createTestBreedParser()}
+  entry := bm.parseAsBirthLine("1111 m angus mãe 5678 pai TOURO1")
+  assert.Equal(t, &BirthEntry{Id: 1111, Dam: 5678, Sex: MALE, Breed: ANGUS, Sire: "TOURO1"}, entry)
+
+  entry = bm.parseAsBirthLine("1112 f nelore touro X dam 42")
+  assert.Equal(t, &BirthEntry{Id: 1112, Dam: 42, Sex: FEMALE, Breed: NELORE, Sire: "X"}, entry)
+
+  entry = bm.parseAsBirthLine("1113 f nelore mae abc")
+  assert.Equal(t, &BirthEntry{Id: 1113, Sex: FEMALE, Breed: NELORE}, entry, "Dam must be a tag")
+}
+
+func TestBirthMessageDamErrors(t *testing.T) {
+  bm := &BirthMessage{BreedParser: createTestBreedParser()}
+  bm.Parse("1111 m angus mãe 5678\n1112 f angus mãe 1111")
+  bm.DamErrors = []*DamError{{1111, 5678, DAM_NOT_FOUND}, {1112, 1111, DAM_NOT_FEMALE}}
+  text := bm.Text("en-US")
+  assert.Contains(t, text, "\nDam 5678 of 1111 is not in the herd, saved without dam.")
+  assert.Contains(t, text, "\nDam 1111 of 1112 is not female, saved without dam.")
+}
+
+func TestBirthMessageDuplicates(t *testing.T) {
+  bm := &BirthMessage{BreedParser: createTestBreedParser()}
+  bm.Parse("1111 m angus\n1112 f angus mãe 1111")
+  assert.Equal(t, 1, bm.Entries[1].line, "Wrong line of the entry")
+  assert.Equal(t, "1112 f angus mãe 1111", bm.Entries[1].text, "Wrong text of the entry")
+
+  bm.Duplicates = 1
+  assert.Contains(t, bm.Text("en-US"), "We added 1 births to area")
+}
+
+func TestBirthLineDetails(t *testing.T) {
+  bm := &BirthMessage{BreedParser: createTestBreedParser()}
+  entry := bm.parseAsBirthLine("1111 m angus 32kg gemeos parto 2")
+  assert.Equal(t, &BirthEntry{Id: 1111, Sex: MALE, Breed: ANGUS, Weight: 32, Twins: true, CalvingEase: 2}, entry)
+
+  entry = bm.parseAsBirthLine("1112 f nelore mãe 5678 28,5 kg")
+  assert.Equal(t, &BirthEntry{Id: 1112, Dam: 5678, Sex: FEMALE, Breed: NELORE, Weight: 28.5}, entry)
+
+  entry = bm.parseAsCalfLine("bez 5678 f nelore gêmeos parto 4")
+  assert.Equal(t, &BirthEntry{Dam: 5678, Sex: FEMALE, Breed: NELORE, Twins: true, CalvingEase: 4}, entry)
+
+  entry = bm.parseAsBirthLine("1113 f nelore parto 9")
+  assert.Equal(t, &BirthEntry{Id: 1113, Sex: FEMALE, Breed: NELORE}, entry, "Calving ease goes from 1 to 5")
+}
@@ -0,0 +1,228 @@
+package chat
+
+import (
+  "fmt"
+  "log"
+  "time"
+  "strconv"
+  "strings"
+  "posso-help/internal/reproduction"
+  "posso-help/internal/scheduler"
+  "posso-help/internal/utils"
+  "go.mongodb.org/mongo-driver/bson"
+)
+
+// Data formats for Breeding data
+// "IA 1234 touro X protocolo cosynch"
+// "IA 1234 1235 touro X"
+// "IATF 1234 protocolo zoetis"
+
+// Keywords that start an insemination line (English and Portuguese)
+var BREEDING_KEYWORDS = []string{"ia", "iatf", "inseminação", "inseminacao", "ai", "insemination"}
+
+// Keywords followed by the sire of the insemination
+var SIRE_KEYWORDS = []string{"touro", "bull", "sire"}
+
+// Keywords followed by the reproduction protocol
+var PROTOCOL_KEYWORDS = []string{"protocolo", "protocol"}
+
+type BreedingEntry struct {
+  Tags []int
+  Sire string
+  Protocol *reproduction.Protocol
+}
+
+type BreedingMessage struct {
+  LineErrors
+  LineClaims
+  // Time the message was received, dates are relative to it
+  Received time.Time
+  Date string
+  Entries []*BreedingEntry
+  ProtocolParser *reproduction.ProtocolParser
+  Total int
+}
+
+// SetDate sets the date of the message, shared by the records
+func (b *BreedingMessage) SetDate(date string) {
+  b.Date = date
+}
+
+func (b *BreedingMessage) GetCollection() string {
+  return "inseminations"
+}
+
+func (b *BreedingMessage) Parse(message string) bool {
+  found := false
+  lines := strings.Split(message, "\n")
+  for index, line := range lines {
+    if date, found := b.parseDateLine(index, line, b.Received); found {
+      b.Date = date
+    }
+    if entry := b.parseAsBreedingLine(line); entry != nil {
+      b.Entries = append(b.Entries, entry)
+      b.Total += len(entry.Tags)
+      b.ClaimLine(index)
+      found = true
+      continue
+    }
+    b.diagnoseBreedingLine(index, line)
+  }
+  return found
+}
+
+func (b *BreedingMessage) parseAsBreedingLine(line string) (*BreedingEntry) {
+  words := strings.Fields(utils.SanitizeLine(line))
+  if len(words) < 2 || !utils.StringIsOneOf(words[0], BREEDING_KEYWORDS) {
+    return nil
+  }
+  // The sire keeps its case, like on birth lines, "touro TOURO1"
+  original := strings.Fields(line)
+
+  entry := &BreedingEntry{}
+  protocolWords := []string{}
+  inProtocol := false
+  for index := 1; index < len(words); index++ {
+    word := words[index]
+    switch {
+    case utils.StringIsOneOf(word, SIRE_KEYWORDS) && index+1 < len(words):
+      index++
+      entry.Sire = words[index]
+      if len(original) == len(words) {
+        entry.Sire = original[index]
+      }
+      inProtocol = false
+    case utils.StringIsOneOf(word, PROTOCOL_KEYWORDS):
+      inProtocol = true
+    case inProtocol:
+      protocolWords = append(protocolWords, word)
+    default:
+      if tag, err := strconv.Atoi(word); err == nil && tag > 0 {
+        entry.Tags = append(entry.Tags, tag)
+      }
+    }
+  }
+
+  if len(entry.Tags) == 0 {
+    return nil
+  }
+
+  if len(protocolWords) > 0 {
+    if b.ProtocolParser == nil {
+      return nil
+    }
+    protocol, found := b.ProtocolParser.MatchProtocol(strings.Join(protocolWords, " "))
+    if !found {
+      log.Printf("Breeding line with unknown protocol: %s", line)
+      return nil
+    }
+    entry.Protocol = protocol
+  }
+
+  return entry
+}
+
+// diagnoseBreedingLine adds a line error when the line is an
+// insemination with tags, but its protocol is not known.
+func (b *BreedingMessage) diagnoseBreedingLine(index int, line string) {
+  words := strings.Fields(utils.SanitizeLine(line))
+  if len(words) < 2 || !utils.StringIsOneOf(words[0], BREEDING_KEYWORDS) {
+    return
+  }
+  tags, protocol := false, false
+  for _, word := range words[1:] {
+    if tag, err := strconv.Atoi(word); err == nil && tag > 0 {
+      tags = true
+    }
+    if utils.StringIsOneOf(word, PROTOCOL_KEYWORDS) {
+      protocol = true
+    }
+  }
+  if tags && protocol {
+    b.AddLineError(index, line, UNKNOWN_PROTOCOL)
+  }
+}
+
+func (b *BreedingMessage) Text(lang string) string {
+  reply := map[string]string {
+    "en-US" : "Zap Manejo has detected insemination data. " +
+              "We added %d inseminations.",
+    "pt-BR" : "Zap Manejo detectou dados de inseminação. " +
+              "Adicionamos %d inseminações.",
+  }
+  protocol := map[string]string {
+    "en-US" : "\nProtocol %s started for %d animals:",
+    "pt-BR" : "\nProtocolo %s iniciado para %d animais:",
+  }
+
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+
+  text := fmt.Sprintf(reply[lang], b.Total)
+  for _, entry := range b.Entries {
+    if entry.Protocol == nil {
+      continue
+    }
+    text += fmt.Sprintf(protocol[lang], entry.Protocol.Name, len(entry.Tags))
+    start, err := time.Parse(time.RFC3339, b.Date)
+    if err != nil {
+      continue
+    }
+    start = start.In(b.Received.Location())
+    for _, step := range entry.Protocol.Schedule(start) {
+      day := start.AddDate(0, 0, step.Day)
+      text += fmt.Sprintf("\nD%d %s %s", step.Day, day.Format("02/01"), step.Event)
+    }
+  }
+  return text
+}
+
+func (b *BreedingMessage) Insert(bmv *BaseMessageValues) error {
+  if b.Date == "" {
+    b.Date = bmv.Date
+  }
+  start, err := time.Parse(time.RFC3339, b.Date)
+  if err != nil {
+    return err
+  }
+  // Steps fall on days of the team
+  start = start.In(b.Received.Location())
+
+  for _, entry := range b.Entries {
+    for _, tag := range entry.Tags {
+      document := bmv.ToMapWithDate(b.Date)
+      document = append(document, bson.E{Key: "tag", Value: tag})
+      document = append(document, bson.E{Key: "sire", Value: entry.Sire})
+      if entry.Protocol != nil {
+        document = append(document, bson.E{Key: "protocol", Value: entry.Protocol.Name})
+      }
+      err := insertRecord(bmv, "inseminations", document)
+      if err != nil {
+        return err
+      }
+
+      if entry.Protocol == nil {
+        continue
+      }
+      id, err := reproduction.StartProtocol(bmv.Account, tag, entry.Protocol, start)
+      if err != nil {
+        return err
+      }
+      bmv.RecordInsert(reproduction.InstancesCollection, id)
+    }
+
+    // Remind the team of the next steps of the protocol
+    if entry.Protocol != nil {
+      ids, err := reproduction.ScheduleReminders(bmv.Account, entry.Protocol, entry.Tags, start, bmv.Name)
+      for _, id := range ids {
+        bmv.RecordInsert(scheduler.TasksCollection, id)
+      }
+      if err != nil {
+        return err
+      }
+    }
+  }
+  return nil
+}
@@ -0,0 +1,56 @@
+package chat
+
+import (
+  "testing"
+  "posso-help/internal/reproduction"
+  "github.com/stretchr/testify/assert"
+)
+
+// Helper to create a mock ProtocolParser for testing
+func createTestProtocolParser() *reproduction.ProtocolParser {
+  pp := &reproduction.ProtocolParser{}
+  pp.AddProtocol(&reproduction.Protocol{
+    Name: "7-day CO-Synch + CIDR",
+    TimelineDays: []*reproduction.Step{
+      {StartDay: 0, EndDay: 0, Event: "GnRH + CIDR"},
+      {StartDay: 7, EndDay: 7, Event: "CIDR removal + PGF2a"},
+      {StartDay: 9, EndDay: 10, Event: "TAI + GnRH"},
+    },
+  })
+  return pp
+}
+
+func TestBreedingMessage(t *testing.T) {
+  bm := &BreedingMessage{ProtocolParser: createTestProtocolParser()}
+  assert.True(t, bm.Parse("IA 1234 touro X protocolo cosynch"), "Could not parse breeding message")
+  assert.Equal(t, 1, bm.Total, "Total inseminations do not match")
+  assert.Equal(t, []int{1234}, bm.Entries[0].Tags, "Wrong tags")
+  assert.Equal(t, "X", bm.Entries[0].Sire, "Wrong sire")
+  assert.Equal(t, "7-day CO-Synch + CIDR", bm.Entries[0].Protocol.Name, "Wrong protocol")
+
+  bm.Date = "2025-03-01T00:00:00Z"
+  text := bm.Text("en-US")
+  assert.Contains(t, text, "D7 08/03 CIDR removal + PGF2a", "Missing protocol step")
+  assert.Contains(t, text, "D9 10/03 TAI + GnRH", "Missing protocol step")
+}
+
+func TestBreedingMessageWithoutProtocol(t *testing.T) {
+  bm := &BreedingMessage{ProtocolParser: createTestProtocolParser()}
+  assert.True(t, bm.Parse("iatf 1234 1235 touro TOURO1"), "Could not parse breeding message")
+  assert.Equal(t, 2, bm.Total, "Total inseminations do not match")
+  assert.Equal(t, "TOURO1", bm.Entries[0].Sire, "The sire keeps its case")
+  assert.Nil(t, bm.Entries[0].Protocol, "Protocol should not be set")
+}
+
+func TestInvalidBreedingLines(t *testing.T) {
+  bm := &BreedingMessage{ProtocolParser: createTestProtocolParser()}
+  assert.Nil(t, bm.parseAsBreedingLine("IA touro X"), "Insemination needs a tag")
+  assert.Nil(t, bm.parseAsBreedingLine("IA 1234 protocolo desconhecido"), "Unknown protocol")
+  assert.Nil(t, bm.parseAsBreedingLine("1234 m angus"), "Birth line is not an insemination")
+}
+
+func TestDiagnoseBreedingLine(t *testing.T) {
+  bm := &BreedingMessage{ProtocolParser: createTestProtocolParser()}
+  assert.False(t, bm.Parse("IA 1234 protocolo ovsynch\nIA touro X"))
+  assert.Equal(t, []*LineError{{1, "IA 1234 protocolo ovsynch", UNKNOWN_PROTOCOL}}, bm.GetLineErrors())
+}
@@ -0,0 +1,200 @@
+package chat
+
+import (
+  "fmt"
+  "log"
+  "time"
+  "strconv"
+  "strings"
+  "context"
+  "posso-help/internal/account"
+  "posso-help/internal/animal"
+  "posso-help/internal/conversation"
+  "posso-help/internal/db"
+  "posso-help/internal/utils"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/bson/primitive"
+  "go.mongodb.org/mongo-driver/mongo"
+)
+
+// Data formats for Calf Tag commands
+// "brinco 5678 bez 1234"
+// "tag 5678 calf 1234"
+
+// Keywords that give an ear tag to the untagged calf of a dam
+var CALF_TAG_KEYWORDS = []string{"brinco", "tag"}
+
+type CalfTagMessage struct {
+  // Time the message was received, dates are shown in its time zone
+  Received time.Time
+  Tag int
+  Dam int
+  // Births record of the calf, found from the dam when nil
+  CalfID interface{}
+  Tagged bool
+  // The tag belongs to another animal of the account
+  Duplicate bool
+  // Question asked when the dam has several untagged calves
+  Pending *conversation.Pending
+}
+
+func (c *CalfTagMessage) GetCollection() string {
+  return "calf_tag"
+}
+
+func (c *CalfTagMessage) Parse(message string) bool {
+  words := strings.Fields(utils.SanitizeLine(message))
+  if len(words) != 4 || !utils.StringIsOneOf(words[0], CALF_TAG_KEYWORDS) ||
+     !utils.StringIsOneOf(words[2], CALF_KEYWORDS) {
+    return false
+  }
+  tag, err := strconv.Atoi(words[1])
+  if err != nil || tag <= 0 {
+    return false
+  }
+  dam, err := strconv.Atoi(words[3])
+  if err != nil || dam <= 0 {
+    return false
+  }
+  c.Tag = tag
+  c.Dam = dam
+  return true
+}
+
+// findUntaggedCalves returns the living calves of the dam without a tag
+func (c *CalfTagMessage) findUntaggedCalves(account string) ([]bson.M, error) {
+  filter := bson.M{
+    "account": account,
+    "dam": c.Dam,
+    "tag": 0,
+    "status": bson.M{"$ne": DEAD},
+  }
+  cursor, err := db.GetCollection("births").Find(context.TODO(), filter)
+  if err != nil {
+    return nil, err
+  }
+  calves := []bson.M{}
+  err = cursor.All(context.TODO(), &calves)
+  return calves, err
+}
+
+// Insert sets the tag of the untagged calf of the dam.  When the dam has
+// several untagged calves nothing is changed, the sender is asked which
+// one gets the tag.
+func (c *CalfTagMessage) Insert(bmv *BaseMessageValues) error {
+  if c.CalfID == nil {
+    calves, err := c.findUntaggedCalves(bmv.Account)
+    if err != nil {
+      return err
+    }
+    if len(calves) == 0 {
+      log.Printf("no untagged calf of dam %d\n", c.Dam)
+      return nil
+    }
+    if len(calves) > 1 {
+      return c.askWhichCalf(bmv, calves)
+    }
+    c.CalfID = calves[0]["_id"]
+  }
+
+  // The unique (account, tag) index of births rejects tags in use
+  filter := bson.M{"_id": c.CalfID, "account": bmv.Account, "tag": 0}
+  previous, err := updateRecord(bmv, "births", filter, bson.M{"tag": c.Tag})
+  if mongo.IsDuplicateKeyError(err) {
+    log.Printf("tag %d already in use\n", c.Tag)
+    c.Duplicate = true
+    return nil
+  }
+  if err != nil {
+    return err
+  }
+  c.Tagged = previous != nil
+  if c.Tagged {
+    birthID, _ := previous["_id"].(primitive.ObjectID)
+    recordAnimal(bmv.Account, &animal.Event{Kind: animal.TAGGED, BirthID: birthID, Tag: c.Tag})
+  }
+  return nil
+}
+
+func (c *CalfTagMessage) askWhichCalf(bmv *BaseMessageValues, calves []bson.M) error {
+  c.Pending = &conversation.Pending{
+    Account: bmv.Account,
+    Phone: bmv.PhoneNumber,
+    Name: bmv.Name,
+    Kind: conversation.CALF_TAG,
+    Value: strconv.Itoa(c.Tag),
+    RawMessage: fmt.Sprintf("brinco %d bez %d", c.Tag, c.Dam),
+    MessageId: bmv.MessageId,
+    Date: bmv.Date,
+  }
+  for _, calf := range calves {
+    id, ok := calf["_id"].(primitive.ObjectID)
+    if !ok {
+      continue
+    }
+    label := fmt.Sprintf("%s %s %s", stringValue(calf["sex"]),
+                         stringValue(calf["breed"]), shortDate(calf["date"], c.Received.Location()))
+    c.Pending.Options = append(c.Pending.Options, &conversation.Option{Value: id.Hex(), Label: label})
+  }
+  return conversation.Save(c.Pending, time.Now())
+}
+
+// answerCalfTag tags the calf the sender chose and returns the reply
+func answerCalfTag(team *account.Team, pending *conversation.Pending, answer int) string {
+  c := &CalfTagMessage{}
+  if !c.Parse(pending.RawMessage) || answer < 1 || answer > len(pending.Options) {
+    log.Printf("invalid calf tag answer %d for %s\n", answer, pending.RawMessage)
+    return HelpText(team.Language)
+  }
+  id, err := primitive.ObjectIDFromHex(pending.Options[answer-1].Value)
+  if err != nil {
+    log.Printf("invalid calf id %s: %v\n", pending.Options[answer-1].Value, err)
+    return HelpText(team.Language)
+  }
+  c.CalfID = id
+
+  bmv := &BaseMessageValues {
+    Account      : pending.Account,
+    PhoneNumber  : pending.Phone,
+    Name         : pending.Name,
+    Date         : pending.Date,
+    MessageId    : pending.MessageId,
+  }
+  if err := c.Insert(bmv); err != nil {
+    log.Printf("Error tagging calf: %v\n", err)
+  }
+  if err := SaveParsedMessage(bmv, pending.RawMessage, c.GetCollection()); err != nil {
+    log.Printf("Error saving parsed message: %v\n", err)
+  }
+  return c.Text(team.Language)
+}
+
+func (c *CalfTagMessage) Text(lang string) string {
+  tagged := map[string]string {
+    "en-US" : "Zap Manejo gave tag %d to the calf of dam %d.",
+    "pt-BR" : "Zap Manejo colocou o brinco %d no bezerro da mãe %d.",
+  }
+  duplicate := map[string]string {
+    "en-US" : "Zap Manejo could not tag the calf, tag %d is already in use.",
+    "pt-BR" : "Zap Manejo não colocou o brinco, o brinco %d já está em uso.",
+  }
+  notFound := map[string]string {
+    "en-US" : "Zap Manejo could not find an untagged calf of dam %d.",
+    "pt-BR" : "Zap Manejo não encontrou bezerro sem brinco da mãe %d.",
+  }
+
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+
+  switch {
+  case c.Pending != nil:
+    return c.Pending.Question(lang)
+  case c.Duplicate:
+    return fmt.Sprintf(duplicate[lang], c.Tag)
+  case c.Tagged:
+    return fmt.Sprintf(tagged[lang], c.Tag, c.Dam)
+  }
+  return fmt.Sprintf(notFound[lang], c.Dam)
+}
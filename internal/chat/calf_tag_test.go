@@ -0,0 +1,34 @@
+package chat
+
+import (
+  "testing"
+  "posso-help/internal/conversation"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestCalfTagMessageParse(t *testing.T) {
+  c := &CalfTagMessage{}
+  assert.True(t, c.Parse("Brinco 5678 bez 1234"), "Could not parse brinco")
+  assert.Equal(t, 5678, c.Tag)
+  assert.Equal(t, 1234, c.Dam)
+  assert.True(t, (&CalfTagMessage{}).Parse("tag 5678 calf 1234"), "Could not parse tag")
+  assert.False(t, (&CalfTagMessage{}).Parse("brinco 5678"), "Calf tag needs a dam")
+  assert.False(t, (&CalfTagMessage{}).Parse("brinco 5678 vaca 1234"), "Calf tag needs a calf keyword")
+  assert.False(t, (&CalfTagMessage{}).Parse("bez 1234 f nelore"), "Calf birth is not a calf tag")
+}
+
+func TestCalfTagMessageText(t *testing.T) {
+  c := &CalfTagMessage{Tag: 5678, Dam: 1234}
+  assert.Equal(t, "Zap Manejo could not find an untagged calf of dam 1234.", c.Text("en-US"))
+  c.Tagged = true
+  assert.Equal(t, "Zap Manejo gave tag 5678 to the calf of dam 1234.", c.Text("en-US"))
+  c.Tagged, c.Duplicate = false, true
+  assert.Equal(t, "Zap Manejo could not tag the calf, tag 5678 is already in use.", c.Text("en-US"))
+
+  c.Pending = &conversation.Pending{Kind: conversation.CALF_TAG, Value: "5678", Options: []*conversation.Option{
+    {Value: "650000000000000000000001", Label: "f nelore 2025-09-10"},
+    {Value: "650000000000000000000002", Label: "m nelore 2025-09-10"},
+  }}
+  assert.Equal(t, "Qual bezerro recebe o brinco 5678? responda com o número\n" +
+                  "1=f nelore 2025-09-10\n2=m nelore 2025-09-10", c.Text("pt-BR"))
+}
@@ -0,0 +1,10 @@
+package calvingtag
+
+import (
+  "posso-help/internal/chat/tag"
+)
+
+// Calving ease score from 1 (unassisted) to 5 (c-section), "parto 2"
+func New() tag.Tag {
+  return tag.NewPattern(`\b(?:parto|calving)\s*([1-5])\b`)
+}
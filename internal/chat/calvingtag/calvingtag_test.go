@@ -0,0 +1,35 @@
+package calvingtag
+
+import (
+  "fmt"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+type TestCase struct {
+  Input string
+  Found bool 
+  Value string
+  ValueInt int
+}
+
+func TestCalvingTag(t *testing.T) {
+  calving := New()
+  tests := []TestCase{
+    {"parto 1",               true,  "1", 1},
+    {"1111 m angus parto 5",  true,  "5", 5},
+    {"calving 3",             true,  "3", 3},
+    {"parto 6",               false, "",  0},
+    {"parto 12",              false, "",  0},
+    {"1111 m angus",          false, "",  0},
+  }
+  for index, test := range tests {
+    found := calving.Parse(test.Input)
+    assert.Equal(t, test.Found, found, 
+                 fmt.Sprintf("test: %d", index))
+    assert.Equal(t, test.Value, calving.Value(), 
+                 fmt.Sprintf("test: %d", index))
+    assert.Equal(t, test.ValueInt, calving.ValueAsInt(), 
+                 fmt.Sprintf("test: %d", index))
+  }
+}
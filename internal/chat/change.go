@@ -0,0 +1,134 @@
+package chat
+
+import (
+  "context"
+  "log"
+  "posso-help/internal/animal"
+  "posso-help/internal/db"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/bson/primitive"
+  "go.mongodb.org/mongo-driver/mongo"
+  "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Change operations
+const INSERT = "insert"
+const UPDATE = "update"
+
+// Change is a record created or updated while inserting a parsed
+// message. Changes are saved with the message in the messages
+// collection so the message can be undone.
+type Change struct {
+  Collection string      `bson:"collection" json:"collection"`
+  Operation  string      `bson:"operation" json:"operation"`
+  ID         interface{} `bson:"id" json:"id"`
+  // Values of the updated fields before the update, nil when the
+  // field did not exist.
+  Previous   bson.M      `bson:"previous,omitempty" json:"previous,omitempty"`
+}
+
+func (bmv *BaseMessageValues) RecordInsert(collection string, id interface{}) {
+  bmv.Changes = append(bmv.Changes, &Change{
+    Collection: collection,
+    Operation: INSERT,
+    ID: id,
+  })
+}
+
+func (bmv *BaseMessageValues) RecordUpdate(collection string, id interface{}, previous bson.M) {
+  bmv.Changes = append(bmv.Changes, &Change{
+    Collection: collection,
+    Operation: UPDATE,
+    ID: id,
+    Previous: previous,
+  })
+}
+
+// insertRecord inserts the document and records the change
+func insertRecord(bmv *BaseMessageValues, collection string, document interface{}) error {
+  result, err := db.GetCollection(collection).InsertOne(context.TODO(), document)
+  if err != nil {
+    return err
+  }
+  bmv.RecordInsert(collection, result.InsertedID)
+  return nil
+}
+
+// lastInsertedID returns the id of the last record inserted for the
+// message, as an ObjectID
+func lastInsertedID(bmv *BaseMessageValues) primitive.ObjectID {
+  for index := len(bmv.Changes) - 1; index >= 0; index-- {
+    if bmv.Changes[index].Operation == INSERT {
+      id, _ := bmv.Changes[index].ID.(primitive.ObjectID)
+      return id
+    }
+  }
+  return primitive.NilObjectID
+}
+
+// recordAnimal keeps the animals registry up to date.  Errors are only
+// logged, the registry can be rebuilt from the events.
+func recordAnimal(account string, event *animal.Event) {
+  if err := animal.Record(account, event); err != nil {
+    log.Printf("Error recording %s event of animal %d: %v\n", event.Kind, event.Tag, err)
+  }
+}
+
+// updateRecord sets the fields on the first record matching the filter
+// and records the previous values.  It returns the record as it was
+// before the update, or nil when no record matched.
+func updateRecord(bmv *BaseMessageValues, collection string, filter bson.M, set bson.M) (bson.M, error) {
+  opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+  previous := bson.M{}
+  err := db.GetCollection(collection).
+    FindOneAndUpdate(context.TODO(), filter, bson.M{"$set": set}, opts).
+    Decode(&previous)
+  if err == mongo.ErrNoDocuments {
+    log.Printf("updateRecord: no %s record matches %v\n", collection, filter)
+    return nil, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+
+  values := bson.M{}
+  for key := range set {
+    values[key] = previous[key]
+  }
+  bmv.RecordUpdate(collection, previous["_id"], values)
+  return previous, nil
+}
+
+// revert undoes the change, deleting inserted records and restoring
+// the previous values of updated ones.
+func (c *Change) revert() error {
+  collection := db.GetCollection(c.Collection)
+  filter := bson.M{"_id": c.ID}
+
+  if c.Operation == INSERT {
+    _, err := collection.DeleteOne(context.TODO(), filter)
+    return err
+  }
+
+  set := bson.M{}
+  unset := bson.M{}
+  for key, value := range c.Previous {
+    if value == nil {
+      unset[key] = ""
+    } else {
+      set[key] = value
+    }
+  }
+  update := bson.M{}
+  if len(set) > 0 {
+    update["$set"] = set
+  }
+  if len(unset) > 0 {
+    update["$unset"] = unset
+  }
+  if len(update) == 0 {
+    return nil
+  }
+  _, err := collection.UpdateOne(context.TODO(), filter, update)
+  return err
+}
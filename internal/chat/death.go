@@ -4,13 +4,25 @@ import (
 	"log"
 	"fmt"
 	"strings"
+	"time"
 	"context"
+	"strconv"
+	"posso-help/internal/animal"
+	"posso-help/internal/cause"
 	"posso-help/internal/db"
-	"posso-help/internal/date"
 	"posso-help/internal/utils"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// Deaths are kept as history records in their own collection, the
+// births record of the animal is only marked as dead.
+const DeathsCollection = "deaths"
+
+// Status of a births record whose animal died
+const DEAD = "dead"
+
 type Death struct {
 	Phone       string `json:"phone"`
 	Name        string `json:"name"`
@@ -23,12 +35,26 @@ type Death struct {
 type DeathEntry struct {
 	Id       int    `json:"tag"`
 	Cause    string `json:"cause"`
+	// Free text after the cause, "1234 raio perto da cerca"
+	Note     string `json:"note"`
 }
 
 type DeathMessage struct {
+	LineErrors
+	LineClaims
+	// Time the message was received, dates are relative to it
+	Received time.Time
 	Date string
 	Entries []*DeathEntry
+	CauseParser *cause.CauseParser
 	Total int
+	// Tags of the message that are not in the herd
+	NotFound []int
+}
+
+// SetDate sets the date of the message, shared by the records
+func (d *DeathMessage) SetDate(date string) {
+	d.Date = date
 }
 
 func (b *DeathMessage) GetCollection() string {
@@ -38,29 +64,64 @@ func (b *DeathMessage) GetCollection() string {
 func (d *DeathMessage) Parse(message string) bool {
 	found := false
 	lines := strings.Split(message, "\n")
-	for _, line := range lines {
-		if date, found := date.ParseAsDateLine(line); found {
+	for index, line := range lines {
+		if date, found := d.parseDateLine(index, line, d.Received); found {
 			d.Date = date
 		}
 		if entry := d.parseAsDeathLine(line); entry != nil {
 			d.Entries = append(d.Entries, entry)
 			d.Total++
+			d.ClaimLine(index)
 			found = true
+			continue
 		}
+		d.diagnoseDeathLine(index, line)
 	}
 	return found 
 }
 
+// parseAsDeathLine reads "{tag} {cause} {note}", the cause is one of the
+// death causes of the account and the note is optional.
 func (d *DeathMessage) parseAsDeathLine(line string) (*DeathEntry) {
-	var num int
-	var cause string
-	line = utils.SanitizeLine(line)
-	n, err := fmt.Sscanf(line, "%d %s", &num, &cause)
-	if err == nil && n == 2 && num > 0 &&
-	(utils.StringIsOneOf(cause, DEATHS)) {
-		return &DeathEntry{Id:num, Cause:cause}
+	tagText, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+	num, err := strconv.Atoi(tagText)
+	if err != nil || num <= 0 {
+		return nil
 	}
-	return nil
+	name, note, found := d.causeParser().MatchCause(rest)
+	if !found {
+		return nil
+	}
+	return &DeathEntry{Id:num, Cause:name, Note:note}
+}
+
+// diagnoseDeathLine adds a line error when the line looks like a death
+// line, "{tag} {cause}", but its cause is not known.  Lines with a sex,
+// numbers or a pregnancy result belong to other parsers.
+func (d *DeathMessage) diagnoseDeathLine(index int, line string) {
+	words, found := startsWithTag(line)
+	if !found || len(words) < 2 || len([]rune(words[1])) < 3 {
+		return
+	}
+	rest := strings.Join(words[1:], " ")
+	if utils.StringIsOneOf(words[1], SEXES) || strings.ContainsAny(rest, "0123456789") ||
+	   looksLikePregnancyResult(words[1]) {
+		return
+	}
+	d.AddLineError(index, line, UNKNOWN_CAUSE)
+}
+
+// causeParser returns the death causes of the account, or the built in
+// DEATHS when the account has none.
+func (d *DeathMessage) causeParser() *cause.CauseParser {
+	if d.CauseParser != nil && len(d.CauseParser.GetCauseNames()) > 0 {
+		return d.CauseParser
+	}
+	defaults := &cause.CauseParser{}
+	for _, name := range DEATHS {
+		defaults.AddCause(name, name)
+	}
+	return defaults
 }
 
 func (d *DeathMessage) Text(lang string) string {
@@ -68,28 +129,83 @@ func (d *DeathMessage) Text(lang string) string {
 		"en-US" : "Zap Manejo has detected death data.  We added %d deaths.",
 		"pt-BR" : "Zap Manejo detectou dados de óbitos. Adicionamos %d óbitos.",
 	}
+	notFound := map[string]string {
+		"en-US" : "\nTags not found in the herd: %s",
+		"pt-BR" : "\nBrincos não encontrados no rebanho: %s",
+	}
 
-	if lang == "pt-BR" ||  lang == "en-US" {
-		return fmt.Sprintf(reply[lang], d.Total)
+	if lang != "pt-BR" && lang != "en-US" {
+		log.Printf("Unsupported or Unknown Language: (%s)", lang)
+		lang = "pt-BR"
 	}
 
-	log.Printf("Unsupported or Unknown Language: (%s)", lang)
-	return fmt.Sprintf(reply["pt-BR"], d.Total)
+	text := fmt.Sprintf(reply[lang], d.Total - len(d.NotFound))
+	if len(d.NotFound) > 0 {
+		tags := []string{}
+		for _, tag := range d.NotFound {
+			tags = append(tags, fmt.Sprintf("%d", tag))
+		}
+		text += fmt.Sprintf(notFound[lang], strings.Join(tags, ", "))
+	}
+	return text
 }
 
+// Insert adds a deaths record for every tag found in the herd and marks
+// the animal as dead, tags that are not found are kept in NotFound.
 func (d *DeathMessage) Insert(bmv *BaseMessageValues) error {
-	collection := db.GetCollection("births")
-	log.Printf("updating death message to collection: %v\n", collection)
+	d.NotFound = nil
+	births := db.GetCollection("births")
 	for _, death := range d.Entries {
-		document := bson.D{bson.E{Key: "cause", Value: death.Cause}}
 		filter := bson.M{"tag": death.Id, "account": bmv.Account}
-		result, err := collection.UpdateOne(context.TODO(), filter, bson.M{"$set": document})
+		birth := bson.M{}
+		err := births.FindOne(context.TODO(), filter).Decode(&birth)
+		if err == mongo.ErrNoDocuments {
+			log.Printf("death of unknown tag %d\n", death.Id)
+			d.NotFound = append(d.NotFound, death.Id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		document := bmv.ToMapWithDate(d.Date)
+		document = append(document, bson.E{Key: "tag", Value: death.Id})
+		document = append(document, bson.E{Key: "cause", Value: death.Cause})
+		if death.Note != "" {
+			document = append(document, bson.E{Key: "note", Value: death.Note})
+		}
+		document = append(document, bson.E{Key: "birth_id", Value: birth["_id"]})
+		document = append(document, bson.E{Key: "area", Value: birth["area"]})
+		document = append(document, bson.E{Key: "message_id", Value: bmv.MessageId})
+		err = insertRecord(bmv, DeathsCollection, document)
 		if err != nil {
 			log.Printf("error inserting death: %v\n", err)
 			return err
 		}
-		log.Printf("update result: %v\n", result)
+
+		dead := bson.M{"status": DEAD, "death_date": dateValue(document)}
+		_, err = updateRecord(bmv, "births", bson.M{"_id": birth["_id"]}, dead)
+		if err != nil {
+			return err
+		}
+		birthID, _ := birth["_id"].(primitive.ObjectID)
+		recordAnimal(bmv.Account, &animal.Event{
+			Kind: animal.DIED,
+			BirthID: birthID,
+			Tag: death.Id,
+			Date: dateValue(document),
+		})
 	}
-	log.Printf("death updated successfully")
 	return nil
 }
+
+// dateValue returns the date of the document
+func dateValue(document bson.D) string {
+	for _, element := range document {
+		if element.Key == "date" {
+			date, _ := element.Value.(string)
+			return date
+		}
+	}
+	return ""
+}
@@ -2,6 +2,8 @@ package chat
 
 import (
   "testing"
+  "time"
+  "posso-help/internal/cause"
   "github.com/stretchr/testify/assert"
 )
 
@@ -23,10 +25,10 @@ func TestDeathMessage(t *testing.T) {
 func TestParseAsDeathLine(t *testing.T) {
   dm := &DeathMessage{}
   tests := []DeathTest {
-    DeathTest{"2235 natimorto", true, &DeathEntry{2235, NATIMORTO}},
-    DeathTest{"2236 Aborto",    true, &DeathEntry{2236, ABORTO}},
-    DeathTest{"1225 Morreu",    true, &DeathEntry{1225, MORREU}},
-    DeathTest{"1226 Morto",     true, &DeathEntry{1226, MORTO}},
+    DeathTest{"2235 natimorto", true, &DeathEntry{2235, NATIMORTO, ""}},
+    DeathTest{"2236 Aborto",    true, &DeathEntry{2236, ABORTO, ""}},
+    DeathTest{"1225 Morreu",    true, &DeathEntry{1225, MORREU, ""}},
+    DeathTest{"1226 Morto",     true, &DeathEntry{1226, MORTO, ""}},
   }
 
   for index, test := range tests {
@@ -53,3 +55,42 @@ func TestParseAsDeathLine(t *testing.T) {
     }
   }
 }
+
+func TestDeathMessageDateYear(t *testing.T) {
+  dm := &DeathMessage{Received: time.Date(2026, time.January, 2, 9, 0, 0, 0, time.UTC)}
+  assert.True(t, dm.Parse("31/12/25\n1234 morreu"), "Could not parse death message")
+  assert.Equal(t, "2025-12-31T00:00:00Z", dm.Date)
+
+  dm = &DeathMessage{Received: time.Date(2026, time.January, 2, 9, 0, 0, 0, time.UTC)}
+  assert.True(t, dm.Parse("31/12\n1234 morreu"), "Could not parse death message")
+  assert.Equal(t, "2025-12-31T00:00:00Z", dm.Date, "31/12 sent in January is from last year")
+}
+
+func TestDeathMessageText(t *testing.T) {
+  dm := &DeathMessage{}
+  dm.Parse("1225 morreu\n1226 morto\n1227 aborto")
+  dm.NotFound = []int{1226, 1227}
+  assert.Equal(t, "Zap Manejo has detected death data.  We added 1 deaths.\n" +
+                  "Tags not found in the herd: 1226, 1227", dm.Text("en-US"))
+}
+
+func TestParseAsDeathLineCatalog(t *testing.T) {
+  cp := &cause.CauseParser{}
+  cp.AddCause("picada de cobra", "picada de cobra;cobra")
+  cp.AddCause("raio", "raio")
+  dm := &DeathMessage{CauseParser: cp}
+
+  death := dm.parseAsDeathLine("1234 Picada de Cobra")
+  assert.Equal(t, &DeathEntry{1234, "picada de cobra", ""}, death)
+  death = dm.parseAsDeathLine("1235 raio perto da cerca")
+  assert.Equal(t, &DeathEntry{1235, "raio", "perto da cerca"}, death)
+  assert.Nil(t, dm.parseAsDeathLine("1236 morreu"), "Causes of the account replace the defaults")
+  assert.Nil(t, dm.parseAsDeathLine("cobra 1236"), "The tag comes first")
+}
+
+func TestDiagnoseDeathLine(t *testing.T) {
+  dm := &DeathMessage{}
+  assert.False(t, dm.Parse("1234 cobra\n1235 m nelore\n1236 prenhx\n1237 350kg\n1238 x\nJupiter"))
+  assert.Equal(t, []*LineError{{1, "1234 cobra", UNKNOWN_CAUSE}}, dm.GetLineErrors(),
+               "Only lines that look like deaths are reported")
+}
@@ -0,0 +1,143 @@
+package chat
+
+import (
+  "sort"
+  "strings"
+)
+
+// Confirmer is implemented by parsers that can make a guess the sender
+// has to confirm before the records are saved.  Confirmation returns the
+// kind of question and the guessed value, Decline drops a guessed value
+// the sender did not confirm.
+type Confirmer interface {
+  Confirmation() (string, string, bool)
+  Decline(value string)
+}
+
+// Dater is implemented by parsers whose records take the date of a date
+// line of the message.
+type Dater interface {
+  MessageDate(skipped map[int]bool) (string, bool)
+  SetDate(date string)
+}
+
+// dispatchMessage returns the parsers of the message, in order.
+//
+// Command parsers (weather, status, summary, undo) answer the whole
+// message, so the first one matching is the only parser of the message.
+// Otherwise every line parser that understands some lines of the message
+// is returned.  The lines claimed by a parser are blanked before the next
+// parser sees the message, so each line is parsed only once, while date
+// and area lines are shared by all of them.  A date line claimed by a
+// later parser, like the rain line "15/02 25mm", is not the date of the
+// records of the others, see parseDateLine.
+//
+// It also returns the indexes of the claimed lines.
+func dispatchMessage(commands []Parser, lineParsers []Parser, message string) ([]Parser, map[int]bool) {
+  claimed := map[int]bool{}
+
+  for _, parser := range commands {
+    if parser.Parse(message) {
+      return []Parser{parser}, claimed
+    }
+  }
+
+  matched := []Parser{}
+  lines := strings.Split(message, "\n")
+  for _, parser := range lineParsers {
+    if !parser.Parse(strings.Join(lines, "\n")) {
+      continue
+    }
+    matched = append(matched, parser)
+    if claimer, ok := parser.(LineClaimer); ok {
+      for _, index := range claimer.ClaimedLines() {
+        claimed[index] = true
+        lines[index] = ""
+      }
+    }
+  }
+
+  for _, parser := range matched {
+    if dater, ok := parser.(Dater); ok {
+      if messageDate, found := dater.MessageDate(claimedByOthers(parser, claimed)); found {
+        dater.SetDate(messageDate)
+      }
+    }
+  }
+  return matched, claimed
+}
+
+// claimedByOthers returns the claimed lines of the message the parser
+// did not claim itself.
+func claimedByOthers(parser Parser, claimed map[int]bool) map[int]bool {
+  others := map[int]bool{}
+  for index := range claimed {
+    others[index] = true
+  }
+  if claimer, ok := parser.(LineClaimer); ok {
+    for _, index := range claimer.ClaimedLines() {
+      delete(others, index)
+    }
+  }
+  return others
+}
+
+// unclaimedLineErrors returns the line errors of the parser for lines
+// no other parser of the message understood.  A parser can report its
+// own lines, when their records could not be saved.
+func unclaimedLineErrors(parser Parser, claimed map[int]bool) []*LineError {
+  reporter, ok := parser.(LineReporter)
+  if !ok {
+    return nil
+  }
+  own := map[int]bool{}
+  if claimer, ok := parser.(LineClaimer); ok {
+    for _, index := range claimer.ClaimedLines() {
+      own[index] = true
+    }
+  }
+  errors := []*LineError{}
+  for _, lineError := range reporter.GetLineErrors() {
+    if !claimed[lineError.Line - 1] || own[lineError.Line - 1] {
+      errors = append(errors, lineError)
+    }
+  }
+  sort.SliceStable(errors, func(i, j int) bool {
+    return errors[i].Line < errors[j].Line
+  })
+  return errors
+}
+
+// mergeLineErrors adds the errors of lines not listed yet, as the same
+// line can be reported by several parsers, keeping the reason first in
+// LINE_ERROR_PRIORITY, and sorts them by line.
+func mergeLineErrors(errors []*LineError, more []*LineError) []*LineError {
+  for _, lineError := range more {
+    listed := false
+    for index, other := range errors {
+      if other.Line == lineError.Line {
+        if lineErrorRank(lineError.Reason) < lineErrorRank(other.Reason) {
+          errors[index] = lineError
+        }
+        listed = true
+        break
+      }
+    }
+    if !listed {
+      errors = append(errors, lineError)
+    }
+  }
+  sort.SliceStable(errors, func(i, j int) bool {
+    return errors[i].Line < errors[j].Line
+  })
+  return errors
+}
+
+func lineErrorRank(reason string) int {
+  for rank, other := range LINE_ERROR_PRIORITY {
+    if other == reason {
+      return rank
+    }
+  }
+  return len(LINE_ERROR_PRIORITY)
+}
@@ -0,0 +1,158 @@
+package chat
+
+import (
+  "os"
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestDispatchMessageSeveralTypes(t *testing.T) {
+  input := `1234 m nelore
+            1235 f angus
+            999 morreu
+            15/02 25mm
+            1236 x nelore
+            Jupiter`
+
+  birth := &BirthMessage{BreedParser: createTestBreedParser()}
+  death := &DeathMessage{}
+  rain := &RainMessage{}
+  commands := []Parser{&StatusMessage{}, &UndoMessage{}}
+  lineParsers := []Parser{death, rain, &TemperatureMessage{}, birth}
+
+  parsers, claimed := dispatchMessage(commands, lineParsers, input)
+  assert.Equal(t, []Parser{death, rain, birth}, parsers, "Wrong parsers")
+  assert.Equal(t, map[int]bool{0: true, 1: true, 2: true, 3: true}, claimed, "Wrong claimed lines")
+
+  assert.Equal(t, 1, death.Total, "Total deaths do not match")
+  assert.Equal(t, 25, rain.Total, "Total rain does not match")
+  assert.Equal(t, 2, birth.Total, "Total births do not match")
+  assert.Equal(t, "jupiter", birth.Area.Name, "Area does not match")
+
+  errors := []*LineError{}
+  for _, parser := range parsers {
+    errors = mergeLineErrors(errors, unclaimedLineErrors(parser, claimed))
+  }
+  assert.Equal(t, []*LineError{{5, "1236 x nelore", INVALID_SEX}}, errors)
+}
+
+func TestDispatchMessageDates(t *testing.T) {
+  received := time.Date(2025, time.March, 20, 9, 0, 0, 0, time.UTC)
+  death := &DeathMessage{Received: received}
+  rain := &RainMessage{Received: received}
+  birth := &BirthMessage{Received: received, BreedParser: createTestBreedParser()}
+  lineParsers := []Parser{death, rain, birth}
+
+  parsers, _ := dispatchMessage(nil, lineParsers, "999 morreu\n15/02 25mm\n1234 m nelore")
+  assert.Equal(t, []Parser{death, rain, birth}, parsers, "Wrong parsers")
+  assert.Equal(t, "", death.Date, "The rain date is not the date of the deaths")
+  assert.Equal(t, "", birth.Date, "The rain date is not the date of the births")
+  assert.Equal(t, "2025-02-15T00:00:00Z", rain.Entries[0].Date)
+
+  death = &DeathMessage{Received: received}
+  rain = &RainMessage{Received: received}
+  birth = &BirthMessage{Received: received, BreedParser: createTestBreedParser()}
+  lineParsers = []Parser{death, rain, birth}
+  dispatchMessage(nil, lineParsers, "18/03\n999 morreu\n15/02 25mm\n1234 m nelore")
+  assert.Equal(t, "2025-03-18T00:00:00Z", death.Date, "Date lines are shared")
+  assert.Equal(t, "2025-03-18T00:00:00Z", birth.Date, "Date lines are shared")
+  assert.Equal(t, "2025-02-15T00:00:00Z", rain.Entries[0].Date, "Rain lines keep their own date")
+}
+
+func TestDispatchMessageDateInText(t *testing.T) {
+  message, err := os.ReadFile("../../test_data/birth_message_02.txt")
+  assert.Nil(t, err, "Could not read the birth message")
+
+  received := time.Date(2025, time.December, 28, 9, 0, 0, 0, time.UTC)
+  death := &DeathMessage{Received: received}
+  rain := &RainMessage{Received: received}
+  birth := &BirthMessage{Received: received, BreedParser: createTestBreedParser()}
+  parsers, _ := dispatchMessage(nil, []Parser{death, rain, birth}, string(message))
+  assert.Equal(t, []Parser{birth}, parsers, "Wrong parsers")
+  assert.Equal(t, "2025-12-25T00:00:00Z", birth.Date, "The date of a text line is the date of the message")
+  assert.Equal(t, 1145, birth.Entries[0].Id)
+}
+
+func TestDispatchMessageNewAreaAfterClaimedLine(t *testing.T) {
+  input := `1234 m nelore
+            Jupiter
+            15/02 25mm`
+
+  birth := &BirthMessage{BreedParser: createTestBreedParser()}
+  parsers, _ := dispatchMessage(nil, []Parser{&RainMessage{}, birth}, input)
+  assert.Equal(t, 2, len(parsers), "Wrong number of parsers")
+  assert.Equal(t, "jupiter", birth.Area.Name, "Claimed lines are not a new area")
+}
+
+func TestDispatchMessageCommand(t *testing.T) {
+  status := &StatusMessage{}
+  birth := &BirthMessage{BreedParser: createTestBreedParser()}
+  parsers, claimed := dispatchMessage([]Parser{status}, []Parser{birth}, "status 1234")
+  assert.Equal(t, []Parser{status}, parsers, "Commands answer the whole message")
+  assert.Equal(t, 0, len(claimed), "Commands do not claim lines")
+  assert.Equal(t, 0, birth.Total, "Line parsers do not run after commands")
+}
+
+func TestDispatchMessageUnparsed(t *testing.T) {
+  parsers, _ := dispatchMessage([]Parser{&StatusMessage{}}, []Parser{&DeathMessage{}}, "hello")
+  assert.Equal(t, 0, len(parsers), "No parser understands the message")
+}
+
+func TestDispatchMessageLineErrors(t *testing.T) {
+  input := `1234 m nelore
+            1235 cobra
+            1236 x nelore
+            1237 350 kgs
+            1238 prenhx`
+
+  birth := &BirthMessage{BreedParser: createTestBreedParser()}
+  lineParsers := []Parser{&DeathMessage{}, &WeightMessage{}, &PregnancyMessage{}, birth}
+  parsers, claimed := dispatchMessage(nil, lineParsers, input)
+  assert.Equal(t, []Parser{birth}, parsers, "Wrong parsers")
+
+  errors := []*LineError{}
+  for _, parser := range lineParsers {
+    errors = mergeLineErrors(errors, unclaimedLineErrors(parser, claimed))
+  }
+  assert.Equal(t, []*LineError{
+    {2, "1235 cobra", UNKNOWN_CAUSE},
+    {3, "1236 x nelore", INVALID_SEX},
+    {4, "1237 350 kgs", BAD_WEIGHT},
+    {5, "1238 prenhx", UNKNOWN_RESULT},
+  }, errors, "Every parser tells why the lines that look like its records were rejected")
+}
+
+func TestUnclaimedLineErrorsOwnLines(t *testing.T) {
+  birth := &BirthMessage{BreedParser: createTestBreedParser()}
+  death := &DeathMessage{}
+  _, claimed := dispatchMessage(nil, []Parser{birth, death}, "1234 m nelore mãe 42\n1235 cobra")
+  birth.AddLineError(0, "1234 m nelore mãe 42", DUPLICATE_TAG)
+  death.AddLineError(0, "1234 m nelore mãe 42", UNKNOWN_CAUSE)
+
+  assert.Equal(t, []*LineError{{1, "1234 m nelore mãe 42", DUPLICATE_TAG}},
+               unclaimedLineErrors(birth, claimed), "A parser reports its own lines")
+  assert.Equal(t, []*LineError{{2, "1235 cobra", UNKNOWN_CAUSE}},
+               unclaimedLineErrors(death, claimed), "Lines of other parsers are not reported")
+}
+
+func TestDispatchMessageBirthBeforePregnancy(t *testing.T) {
+  pregnancy := &PregnancyMessage{}
+  birth := &BirthMessage{BreedParser: createTestBreedParser()}
+  parsers, _ := dispatchMessage(nil, []Parser{pregnancy, birth}, "1234 m angus prenha\n1235 vazia")
+  assert.Equal(t, []Parser{pregnancy, birth}, parsers, "Wrong parsers")
+  assert.Equal(t, 1, len(pregnancy.Entries), "The birth line is not a pregnancy check")
+  assert.Equal(t, 1235, pregnancy.Entries[0].Id)
+  assert.Equal(t, MALE, birth.Entries[0].Sex, "The sex of the birth line is kept")
+  assert.Equal(t, ANGUS, birth.Entries[0].Breed, "The breed of the birth line is kept")
+}
+
+func TestMergeLineErrors(t *testing.T) {
+  errors := mergeLineErrors(nil, []*LineError{{3, "31/02", BAD_DATE}})
+  errors = mergeLineErrors(errors, []*LineError{{1, "1 x y", INVALID_SEX}, {3, "31/02", BAD_DATE}})
+  assert.Equal(t, []*LineError{{1, "1 x y", INVALID_SEX}, {3, "31/02", BAD_DATE}}, errors)
+
+  errors = mergeLineErrors(errors, []*LineError{{1, "1 x y", BAD_WEIGHT}, {3, "31/02", UNKNOWN_CAUSE}})
+  assert.Equal(t, []*LineError{{1, "1 x y", BAD_WEIGHT}, {3, "31/02", BAD_DATE}}, errors,
+               "The reason first in LINE_ERROR_PRIORITY is kept")
+}
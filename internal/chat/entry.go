@@ -7,7 +7,12 @@ import (
   "time"
   "posso-help/internal/area"
   "posso-help/internal/breed"
+  "posso-help/internal/cause"
   "posso-help/internal/account"
+  "posso-help/internal/conversation"
+  "posso-help/internal/date"
+  "posso-help/internal/product"
+  "posso-help/internal/reproduction"
   "posso-help/internal/textmsg"
 )
 
@@ -75,15 +80,6 @@ func ProcessEntries(entries []Entry) error {
 
 func (e Entry) Process() error {
 
-  birthMessageParser := &BirthMessage{}
-  parsers := []Parser{
-    &DeathMessage{},
-    birthMessageParser,
-    &RainMessage{},
-    &TemperatureMessage{},
-    &WeatherMessage{},
-  }
-
   for _, change := range e.Changes {
 
     name := "unknown"
@@ -100,7 +96,6 @@ func (e Entry) Process() error {
       }
 
       unixTimestamp := int64(timestamp)
-      t := time.Unix(unixTimestamp, 0)
 
       team, err := account.FindAccountByPhoneNumber(message.From)
       if (err != nil) {
@@ -113,49 +108,224 @@ func (e Entry) Process() error {
           message.From = team.PhoneNumber
         }
       }
-
-      areaParser := &area.AreaParser{}
-      err = areaParser.LoadAreasByAccount(team.Account)
-      if err != nil {
-        log.Printf("WARNING: Could not load areas from account: %v\n", team.Account)
-      }
-      birthMessageParser.AreaParser = areaParser
-
-      breedParser := &breed.BreedParser{}
-      err = breedParser.LoadBreedsByAccount(team.Account)
-      if err != nil {
-        log.Printf("WARNING: Could not load breeds from account: %v\n", team.Account)
-      }
-      birthMessageParser.BreedParser = breedParser
       baseMessageValues := &BaseMessageValues {
         Account      : team.Account,
         PhoneNumber  : message.From,
         Name         : name,
-        Date         : t.Format(time.RFC3339),
+        Date         : date.UTC(time.Unix(unixTimestamp, 0)),
+        MessageId    : message.ID,
       }
 
-      for _, parser := range parsers {
-        msg := strings.TrimSpace(message.Text.Body)
-        if found := parser.Parse(msg); found {
-          log.Printf("message parsed with parser: %v\n", parser.GetCollection())
-          if err := parser.Insert(baseMessageValues); err != nil {
-            log.Printf("Error insert record into DB: %v\n", err)
-          }
-          // Save the parsed message to the messages collection
-          if err := SaveParsedMessage(baseMessageValues, msg, parser.GetCollection()); err != nil {
-            log.Printf("Error saving parsed message: %v\n", err)
-          }
-          text := textmsg.NewMessageSender(message.From, parser.Text(team.Language))
-          if err := text.Send(); err != nil {
-            log.Printf("Error during text reply: %v\n", err)
-          }
-          // A single message can only be parsed by one parser.
-          // Note, if we ever change this, births is a subset of deaths.
-          break
-        }
+      msg := strings.TrimSpace(message.Text.Body)
+
+      // A reply to a question asked about an earlier message
+      var reply string
+      pending, err := conversation.Find(team.Account, message.From, time.Now())
+      if err != nil {
+        log.Printf("Error reading pending conversation: %v\n", err)
+      }
+      answer := conversation.NONE
+      if pending != nil {
+        answer = pending.ParseAnswer(msg)
+      }
+      if answer != conversation.NONE {
+        reply = answerPending(team, pending, answer)
+      } else {
+        reply = processMessage(team, baseMessageValues, msg)
+      }
+
+      text := textmsg.NewMessageSender(message.From, reply)
+      if err := text.Send(); err != nil {
+        log.Printf("Error during text reply: %v\n", err)
       }
     }
   }
 
   return nil
 }
+
+// processMessage parses the message, inserts its records and returns
+// the reply.  When a parser needs the sender to confirm a guess, nothing
+// is inserted, the message is kept as a pending conversation and the
+// reply is the question.  Declined are the new areas the sender did not
+// confirm, their records are saved without area.
+func processMessage(team *account.Team, bmv *BaseMessageValues, msg string, declined ...string) string {
+  // Dates in the message are relative to the time it was received, in
+  // the time zone of the team, so dd/mm dates and evening messages fall
+  // on the day the team sees.
+  received, err := time.Parse(time.RFC3339, bmv.Date)
+  if err != nil {
+    received = time.Now()
+  }
+  received = received.In(team.Location())
+  commands, lineParsers := newParsers(team, received)
+  parsers, claimed := dispatchMessage(commands, lineParsers, msg)
+
+  for _, parser := range parsers {
+    confirmer, ok := parser.(Confirmer)
+    if !ok {
+      continue
+    }
+    for _, value := range declined {
+      confirmer.Decline(value)
+    }
+    if kind, value, needed := confirmer.Confirmation(); needed {
+      pending := &conversation.Pending{
+        Account: bmv.Account,
+        Phone: bmv.PhoneNumber,
+        Name: bmv.Name,
+        Kind: kind,
+        Value: value,
+        RawMessage: msg,
+        MessageId: bmv.MessageId,
+        Date: bmv.Date,
+        Declined: declined,
+      }
+      if err := conversation.Save(pending, time.Now()); err != nil {
+        log.Printf("Error saving pending conversation: %v\n", err)
+      }
+      return pending.Question(team.Language)
+    }
+  }
+
+  // Every parser inserts its records and is saved as its own
+  // document in the messages collection, sharing the message id.
+  replies := []string{}
+  lineErrors := []*LineError{}
+  for _, parser := range parsers {
+    log.Printf("message parsed with parser: %v\n", parser.GetCollection())
+    bmv.Changes = nil
+    if err := parser.Insert(bmv); err != nil {
+      log.Printf("Error insert record into DB: %v\n", err)
+    }
+    // Lines of the message no parser could understand are saved
+    // with the message and listed in the reply.
+    bmv.LineErrors = unclaimedLineErrors(parser, claimed)
+    lineErrors = mergeLineErrors(lineErrors, bmv.LineErrors)
+    // Save the parsed message to the messages collection
+    if err := SaveParsedMessage(bmv, msg, parser.GetCollection()); err != nil {
+      log.Printf("Error saving parsed message: %v\n", err)
+    }
+    replies = append(replies, parser.Text(team.Language))
+  }
+
+  // Line parsers that found no records of their own can still tell why
+  // a line was not understood.
+  for _, parser := range lineParsers {
+    lineErrors = mergeLineErrors(lineErrors, unclaimedLineErrors(parser, claimed))
+  }
+
+  // Save what no parser understood so it can be triaged from the
+  // dashboard, and reply with the accepted formats.
+  if len(parsers) == 0 {
+    log.Printf("message not parsed: %s\n", msg)
+    bmv.LineErrors = lineErrors
+    if err := SaveParsedMessage(bmv, msg, UNPARSED); err != nil {
+      log.Printf("Error saving unparsed message: %v\n", err)
+    }
+    replies = append(replies, HelpText(team.Language))
+  }
+
+  return strings.Join(replies, "\n\n") + LineErrorsText(team.Language, lineErrors)
+}
+
+// answerPending commits the pending message according to the answer of
+// the sender, and returns the reply.  When the new area is not confirmed,
+// or can not be created, the records are saved without it.
+func answerPending(team *account.Team, pending *conversation.Pending, answer int) string {
+  if err := conversation.Delete(pending); err != nil {
+    log.Printf("Error deleting pending conversation: %v\n", err)
+  }
+  if pending.Kind == conversation.CALF_TAG {
+    return answerCalfTag(team, pending, answer)
+  }
+
+  bmv := &BaseMessageValues {
+    Account      : pending.Account,
+    PhoneNumber  : pending.Phone,
+    Name         : pending.Name,
+    Date         : pending.Date,
+    MessageId    : pending.MessageId,
+  }
+
+  declined := pending.Declined
+  notice := ""
+  if pending.Kind == conversation.NEW_AREA && answer == conversation.NO {
+    log.Printf("pending %s declined: %s\n", pending.Kind, pending.Value)
+    declined = append(declined, pending.Value)
+    notice = pending.Discarded(team.Language, false) + "\n\n"
+  } else if pending.Kind == conversation.NEW_AREA {
+    id, err := area.AddArea(pending.Account, pending.Value, pending.Value)
+    if err != nil {
+      log.Printf("Could not add new area %v\n", err)
+      declined = append(declined, pending.Value)
+      notice = pending.Discarded(team.Language, true) + "\n\n"
+    } else {
+      // Saved with the message id, so undo also removes the area
+      bmv.RecordInsert("areas", id)
+      if err := SaveParsedMessage(bmv, pending.RawMessage, "areas"); err != nil {
+        log.Printf("Error saving parsed message: %v\n", err)
+      }
+    }
+  }
+
+  // The message can have another new area to confirm
+  return notice + processMessage(team, bmv, pending.RawMessage, declined...)
+}
+
+// newParsers returns the command parsers and the line parsers of a
+// message received at the given time, loaded with the areas, breeds,
+// products, protocols and death causes of the team account.  Birth is
+// the last line parser, since the last line of the message not
+// understood by any parser can be the name of a new area.
+func newParsers(team *account.Team, received time.Time) ([]Parser, []Parser) {
+  areaParser := &area.AreaParser{}
+  err := areaParser.LoadAreasByAccount(team.Account)
+  if err != nil {
+    log.Printf("WARNING: Could not load areas from account: %v\n", team.Account)
+  }
+
+  breedParser := &breed.BreedParser{Threshold: team.BreedMatchThreshold}
+  err = breedParser.LoadBreedsByAccount(team.Account)
+  if err != nil {
+    log.Printf("WARNING: Could not load breeds from account: %v\n", team.Account)
+  }
+
+  productParser := &product.ProductParser{}
+  err = productParser.LoadProductsByAccount(team.Account)
+  if err != nil {
+    log.Printf("WARNING: Could not load products from account: %v\n", team.Account)
+  }
+
+  protocolParser := &reproduction.ProtocolParser{}
+  err = protocolParser.LoadProtocolsByAccount(team.Account)
+  if err != nil {
+    log.Printf("WARNING: Could not load protocols from account: %v\n", team.Account)
+  }
+
+  causeParser := &cause.CauseParser{}
+  err = causeParser.LoadCausesByAccount(team.Account)
+  if err != nil {
+    log.Printf("WARNING: Could not load death causes from account: %v\n", team.Account)
+  }
+
+  commands := []Parser{
+    &WeatherMessage{},
+    &StatusMessage{Received: received},
+    &SummaryMessage{Received: received},
+    &UndoMessage{},
+    &CalfTagMessage{Received: received},
+  }
+  lineParsers := []Parser{
+    &DeathMessage{Received: received, CauseParser: causeParser},
+    &RainMessage{Received: received, AreaParser: areaParser},
+    &TemperatureMessage{Received: received},
+    &WeightMessage{Received: received, AreaParser: areaParser},
+    &TreatmentMessage{Received: received, ProductParser: productParser},
+    &MovementMessage{Received: received, AreaParser: areaParser},
+    &PregnancyMessage{Received: received, AreaParser: areaParser},
+    &BreedingMessage{Received: received, ProtocolParser: protocolParser},
+    &BirthMessage{Received: received, AreaParser: areaParser, BreedParser: breedParser},
+  }
+  return commands, lineParsers
+}
@@ -0,0 +1,51 @@
+package chat
+
+import (
+  "log"
+)
+
+// HelpText returns the localized reply to messages no parser understood,
+// with an example of each accepted format.
+func HelpText(lang string) string {
+  reply := map[string]string {
+    "en-US" : "Zap Manejo did not understand your message. Accepted formats:\n" +
+              "Birth: 1234 m nelore\n" +
+              "Calf: calf 1234 f nelore\n" +
+              "Calf tag: tag 5678 calf 1234\n" +
+              "Death: 1234 morreu\n" +
+              "Rain: 15/02 25mm\n" +
+              "Temperature: 15/02 35c\n" +
+              "Weight: 1234 320kg\n" +
+              "Treatment: vaccine aftosa 1234 1235\n" +
+              "Movement: move 1234 1235 to north pasture\n" +
+              "Pregnancy check: 1234 pregnant\n" +
+              "Insemination: AI 1234 bull X\n" +
+              "Animal status: status 1234\n" +
+              "Herd summary: summary\n" +
+              "Undo the last message: undo\n" +
+              "A date (dd/mm) or area on its own line applies to the whole message.",
+    "pt-BR" : "Zap Manejo não entendeu sua mensagem. Formatos aceitos:\n" +
+              "Nascimento: 1234 m nelore\n" +
+              "Bezerro: bez 1234 f nelore\n" +
+              "Brinco do bezerro: brinco 5678 bez 1234\n" +
+              "Óbito: 1234 morreu\n" +
+              "Chuva: 15/02 25mm\n" +
+              "Temperatura: 15/02 35c\n" +
+              "Peso: 1234 320kg\n" +
+              "Tratamento: vacina aftosa 1234 1235\n" +
+              "Movimentação: mover 1234 1235 para pasto norte\n" +
+              "Diagnóstico de prenhez: 1234 prenha\n" +
+              "Inseminação: IA 1234 touro X\n" +
+              "Ficha do animal: ficha 1234\n" +
+              "Resumo do rebanho: resumo\n" +
+              "Desfazer a última mensagem: desfazer\n" +
+              "Uma data (dd/mm) ou área em uma linha vale para toda a mensagem.",
+  }
+
+  if lang == "pt-BR" ||  lang == "en-US" {
+    return reply[lang]
+  }
+
+  log.Printf("Unsupported or Unknown Language: (%s)", lang)
+  return reply["pt-BR"]
+}
@@ -0,0 +1,12 @@
+package chat
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestHelpText(t *testing.T) {
+  assert.Contains(t, HelpText("en-US"), "Birth: 1234 m nelore")
+  assert.Contains(t, HelpText("pt-BR"), "Nascimento: 1234 m nelore")
+  assert.Equal(t, HelpText("pt-BR"), HelpText("es-ES"), "Unknown languages fall back to pt-BR")
+}
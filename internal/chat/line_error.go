@@ -0,0 +1,208 @@
+package chat
+
+import (
+  "fmt"
+  "log"
+  "strconv"
+  "strings"
+  "time"
+  "posso-help/internal/date"
+  "posso-help/internal/utils"
+)
+
+// Reasons a line of a message was not understood
+const UNKNOWN_BREED = "unknown_breed"
+const INVALID_SEX = "invalid_sex"
+const BAD_DATE = "bad_date"
+const BAD_WEIGHT = "bad_weight"
+const DUPLICATE_TAG = "duplicate_tag"
+const UNKNOWN_CAUSE = "unknown_cause"
+const UNKNOWN_PRODUCT = "unknown_product"
+const UNKNOWN_RESULT = "unknown_result"
+const UNKNOWN_PROTOCOL = "unknown_protocol"
+const NO_DESTINATION = "no_destination"
+
+// When several parsers report the same line, the reason listed first
+// is kept, as it comes from the parser that recognized more of the line.
+var LINE_ERROR_PRIORITY = []string{
+  DUPLICATE_TAG, BAD_DATE, BAD_WEIGHT, UNKNOWN_PRODUCT, UNKNOWN_PROTOCOL, NO_DESTINATION,
+  UNKNOWN_RESULT, INVALID_SEX, UNKNOWN_BREED, UNKNOWN_CAUSE,
+}
+
+// LineError is a line of a message the parser could not understand
+type LineError struct {
+  Line   int    `bson:"line" json:"line"` // line number, starting at 1
+  Text   string `bson:"text" json:"text"`
+  Reason string `bson:"reason" json:"reason"`
+}
+
+// LineReporter is implemented by parsers that report the lines of the
+// message they could not understand.
+type LineReporter interface {
+  GetLineErrors() []*LineError
+}
+
+// LineErrors is embedded in parsers to collect their line errors
+type LineErrors struct {
+  Errors []*LineError
+  // Dates of the date lines, by line index
+  dates map[int]string
+}
+
+func (l *LineErrors) GetLineErrors() []*LineError {
+  return l.Errors
+}
+
+func (l *LineErrors) AddLineError(index int, text, reason string) {
+  l.Errors = append(l.Errors, &LineError{
+    Line: index + 1,
+    Text: strings.TrimSpace(text),
+    Reason: reason,
+  })
+}
+
+// MessageDate returns the date of the last date line of the message
+// not in the skipped lines, false when the message has no date line.
+func (l *LineErrors) MessageDate(skipped map[int]bool) (string, bool) {
+  messageDate, last := "", -1
+  for index, parsed := range l.dates {
+    if !skipped[index] && index > last {
+      messageDate, last = parsed, index
+    }
+  }
+  return messageDate, len(l.dates) > 0
+}
+
+// startsWithTag returns the words of the line and whether the first one
+// is an ear tag, as most data lines start with the tag of the animal.
+func startsWithTag(line string) ([]string, bool) {
+  words := strings.Fields(utils.SanitizeLine(line))
+  if len(words) == 0 {
+    return words, false
+  }
+  tag, err := strconv.Atoi(words[0])
+  return words, err == nil && tag > 0
+}
+
+// HasLineError reports whether a line error was added for the line
+func (l *LineErrors) HasLineError(index int) bool {
+  for _, lineError := range l.Errors {
+    if lineError.Line == index + 1 {
+      return true
+    }
+  }
+  return false
+}
+
+// parseDateLine parses the line as a date line, adding a BAD_DATE line
+// error when the line has a date that does not exist.  The year of dd/mm
+// dates is inferred from the time the message was received.  Any line
+// not starting with a tag can hold the date, like "Nascimento de 25/12",
+// but the date of a line claimed by another parser, like the rain line
+// "15/02 25mm", belongs to its record, see dispatchMessage.
+func (l *LineErrors) parseDateLine(index int, line string, received time.Time) (string, bool) {
+  if _, tagged := startsWithTag(line); tagged {
+    return "", false
+  }
+  if parsed, found := date.ParseAsDateLine(line, received); found {
+    if l.dates == nil {
+      l.dates = map[int]string{}
+    }
+    l.dates[index] = parsed
+    return parsed, true
+  }
+  if date.HasBadDate(line) {
+    l.AddLineError(index, line, BAD_DATE)
+  }
+  return "", false
+}
+
+// LineErrorsText returns the localized list of lines that were not
+// understood, to be appended to the reply of the parser.
+func LineErrorsText(lang string, errors []*LineError) string {
+  header := map[string]string {
+    "en-US" : "\nLines not understood:",
+    "pt-BR" : "\nLinhas não entendidas:",
+  }
+  reasons := map[string]map[string]string {
+    UNKNOWN_BREED : {
+      "en-US" : "unknown breed",
+      "pt-BR" : "raça desconhecida",
+    },
+    INVALID_SEX : {
+      "en-US" : "invalid sex",
+      "pt-BR" : "sexo inválido",
+    },
+    BAD_DATE : {
+      "en-US" : "bad date",
+      "pt-BR" : "data inválida",
+    },
+    DUPLICATE_TAG : {
+      "en-US" : "tag already in the herd, not saved",
+      "pt-BR" : "brinco já existe no rebanho, não salvo",
+    },
+    BAD_WEIGHT : {
+      "en-US" : "bad weight",
+      "pt-BR" : "peso inválido",
+    },
+    UNKNOWN_CAUSE : {
+      "en-US" : "unknown death cause",
+      "pt-BR" : "causa de óbito desconhecida",
+    },
+    UNKNOWN_PRODUCT : {
+      "en-US" : "unknown product",
+      "pt-BR" : "produto desconhecido",
+    },
+    UNKNOWN_RESULT : {
+      "en-US" : "unknown pregnancy result",
+      "pt-BR" : "resultado de prenhez desconhecido",
+    },
+    UNKNOWN_PROTOCOL : {
+      "en-US" : "unknown protocol",
+      "pt-BR" : "protocolo desconhecido",
+    },
+    NO_DESTINATION : {
+      "en-US" : "missing destination area",
+      "pt-BR" : "área de destino faltando",
+    },
+  }
+
+  if len(errors) == 0 {
+    return ""
+  }
+
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+
+  text := header[lang]
+  for _, lineError := range errors {
+    reason := lineError.Reason
+    if localized, ok := reasons[reason]; ok {
+      reason = localized[lang]
+    }
+    text += fmt.Sprintf("\n  %d: \"%s\" (%s)", lineError.Line, lineError.Text, reason)
+  }
+  return text
+}
+
+// LineClaimer is implemented by parsers of data lines, so a message can
+// hold records of several parsers.  It returns the indexes of the lines
+// the parser understood as its records.
+type LineClaimer interface {
+  ClaimedLines() []int
+}
+
+// LineClaims is embedded in parsers to collect their claimed lines
+type LineClaims struct {
+  Claimed []int
+}
+
+func (l *LineClaims) ClaimedLines() []int {
+  return l.Claimed
+}
+
+func (l *LineClaims) ClaimLine(index int) {
+  l.Claimed = append(l.Claimed, index)
+}
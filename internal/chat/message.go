@@ -3,19 +3,36 @@ package chat
 import (
 	"context"
 	"log"
+	"time"
 	"posso-help/internal/db"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 const MessagesCollection = "messages"
 
+// Message type of the messages no parser understood
+const UNPARSED = "unparsed"
+
 type ParsedMessage struct {
-	Account     string `bson:"account" json:"account"`
-	PhoneNumber string `bson:"phone" json:"phone"`
-	Name        string `bson:"name" json:"name"`
-	Date        string `bson:"date" json:"date"`
-	RawMessage  string `bson:"raw_message" json:"raw_message"`
-	MessageType string `bson:"message_type" json:"message_type"`
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Account     string             `bson:"account" json:"account"`
+	PhoneNumber string             `bson:"phone" json:"phone"`
+	Name        string             `bson:"name" json:"name"`
+	Date        string             `bson:"date" json:"date"`
+	RawMessage  string             `bson:"raw_message" json:"raw_message"`
+	MessageType string             `bson:"message_type" json:"message_type"`
+	MessageId   string             `bson:"message_id,omitempty" json:"message_id,omitempty"`
+	Changes     []*Change          `bson:"changes,omitempty" json:"changes,omitempty"`
+	LineErrors  []*LineError       `bson:"line_errors,omitempty" json:"line_errors,omitempty"`
+	Reverted    bool               `bson:"reverted,omitempty" json:"reverted,omitempty"`
+	RevertedBy  string             `bson:"reverted_by,omitempty" json:"reverted_by,omitempty"`
+	RevertedAt  string             `bson:"reverted_at,omitempty" json:"reverted_at,omitempty"`
+	Resolved    bool               `bson:"resolved,omitempty" json:"resolved"`
+	ResolvedBy  string             `bson:"resolved_by,omitempty" json:"resolved_by,omitempty"`
+	ResolvedAt  string             `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
 }
 
 func SaveParsedMessage(bmv *BaseMessageValues, rawMessage string, messageType string) error {
@@ -28,6 +45,9 @@ func SaveParsedMessage(bmv *BaseMessageValues, rawMessage string, messageType st
 		"date":         bmv.Date,
 		"raw_message":  rawMessage,
 		"message_type": messageType,
+		"message_id":   bmv.MessageId,
+		"changes":      bmv.Changes,
+		"line_errors":  bmv.LineErrors,
 	}
 
 	_, err := collection.InsertOne(context.TODO(), doc)
@@ -39,3 +59,40 @@ func SaveParsedMessage(bmv *BaseMessageValues, rawMessage string, messageType st
 	log.Printf("Saved parsed message to messages collection: type=%s\n", messageType)
 	return nil
 }
+
+// FindUnparsedMessages returns the messages of the account no parser
+// understood, newest first.  Resolved messages are only returned when
+// includeResolved is set.
+func FindUnparsedMessages(account string, includeResolved bool) ([]*ParsedMessage, error) {
+	filter := bson.M{"account": account, "message_type": UNPARSED}
+	if !includeResolved {
+		filter["resolved"] = bson.M{"$ne": true}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
+	cursor, err := db.GetCollection(MessagesCollection).Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	messages := []*ParsedMessage{}
+	err = cursor.All(context.TODO(), &messages)
+	return messages, err
+}
+
+// ResolveUnparsedMessage marks the unparsed message as resolved, it
+// returns mongo.ErrNoDocuments when the account has no such message.
+func ResolveUnparsedMessage(account string, id primitive.ObjectID, resolvedBy string) error {
+	filter := bson.M{"_id": id, "account": account, "message_type": UNPARSED}
+	update := bson.M{"$set": bson.M{
+		"resolved":    true,
+		"resolved_by": resolvedBy,
+		"resolved_at": time.Now().UTC().Format(time.RFC3339),
+	}}
+	result, err := db.GetCollection(MessagesCollection).UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
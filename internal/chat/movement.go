@@ -0,0 +1,216 @@
+package chat
+
+import (
+  "fmt"
+  "log"
+  "strconv"
+  "strings"
+  "time"
+  "posso-help/internal/animal"
+  "posso-help/internal/area"
+  "posso-help/internal/conversation"
+  "posso-help/internal/utils"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Data formats for Movement data
+// "mover 1234 1235 para pasto norte"
+// "move 1234 to north pasture"
+
+// Keywords that start a movement line (English and Portuguese)
+var MOVEMENT_KEYWORDS = []string{"mover", "mova", "moveu", "transferir", "move", "transfer"}
+
+// Words separating the tags from the destination area
+var MOVEMENT_DESTINATION = []string{"para", "pra", "p/", "to"}
+
+type MovementEntry struct {
+  Tags []int
+  Destination string
+  NewArea bool
+}
+
+type MovementMessage struct {
+  LineErrors
+  LineClaims
+  // Time the message was received, dates are relative to it
+  Received time.Time
+  Date string
+  Entries []*MovementEntry
+  AreaParser *area.AreaParser
+  NotFound []int
+  Total int
+}
+
+// SetDate sets the date of the message, shared by the records
+func (m *MovementMessage) SetDate(date string) {
+  m.Date = date
+}
+
+func (m *MovementMessage) GetCollection() string {
+  return "movements"
+}
+
+func (m *MovementMessage) Parse(message string) bool {
+  found := false
+  lines := strings.Split(message, "\n")
+  for index, line := range lines {
+    if date, found := m.parseDateLine(index, line, m.Received); found {
+      m.Date = date
+    }
+    if entry := m.parseAsMovementLine(line); entry != nil {
+      m.Entries = append(m.Entries, entry)
+      m.Total += len(entry.Tags)
+      m.ClaimLine(index)
+      found = true
+      continue
+    }
+    m.diagnoseMovementLine(index, line)
+  }
+  return found
+}
+
+// Confirmation asks the sender to confirm a new destination area before
+// the animals are moved, so typos do not create areas.
+func (m *MovementMessage) Confirmation() (string, string, bool) {
+  for _, entry := range m.Entries {
+    if entry.NewArea {
+      return conversation.NEW_AREA, entry.Destination, true
+    }
+  }
+  return "", "", false
+}
+
+// Decline moves the animals to no area when the new destination was not
+// confirmed
+func (m *MovementMessage) Decline(value string) {
+  for _, entry := range m.Entries {
+    if entry.NewArea && entry.Destination == value {
+      entry.Destination = "unknown"
+      entry.NewArea = false
+    }
+  }
+}
+
+func (m *MovementMessage) parseAsMovementLine(line string) (*MovementEntry) {
+  words := strings.Fields(utils.SanitizeLine(line))
+  if len(words) < 4 || !utils.StringIsOneOf(words[0], MOVEMENT_KEYWORDS) {
+    return nil
+  }
+
+  entry := &MovementEntry{}
+  for index, word := range words[1:] {
+    if tag, err := strconv.Atoi(word); err == nil && tag > 0 {
+      entry.Tags = append(entry.Tags, tag)
+      continue
+    }
+    if utils.StringIsOneOf(word, MOVEMENT_DESTINATION) {
+      entry.Destination = strings.Join(words[index+2:], " ")
+    }
+    break
+  }
+
+  if len(entry.Tags) == 0 || entry.Destination == "" {
+    return nil
+  }
+
+  // Resolve the destination against the known areas of the account,
+  // if it is not known the sender is asked to confirm the new area.
+  if m.AreaParser != nil {
+    if areaName, found := m.AreaParser.ParseAsAreaLine(entry.Destination); found {
+      entry.Destination = areaName
+      return entry
+    }
+  }
+  log.Printf("New Area Found \"%s\"", entry.Destination)
+  entry.NewArea = true
+  return entry
+}
+
+// diagnoseMovementLine adds a line error when the line moves tags but
+// has no destination area, "mover 1234 1235".
+func (m *MovementMessage) diagnoseMovementLine(index int, line string) {
+  words := strings.Fields(utils.SanitizeLine(line))
+  if len(words) < 2 || !utils.StringIsOneOf(words[0], MOVEMENT_KEYWORDS) {
+    return
+  }
+  if tag, err := strconv.Atoi(words[1]); err == nil && tag > 0 {
+    m.AddLineError(index, line, NO_DESTINATION)
+  }
+}
+
+func (m *MovementMessage) Text(lang string) string {
+  reply := map[string]string {
+    "en-US" : "Zap Manejo has detected movement data. " +
+              "We moved %d animals to %s.",
+    "pt-BR" : "Zap Manejo detectou dados de movimentação. " +
+              "Movemos %d animais para %s.",
+  }
+  notFound := map[string]string {
+    "en-US" : "\nTags not found in the herd: %s",
+    "pt-BR" : "\nBrincos não encontrados no rebanho: %s",
+  }
+
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+
+  destinations := []string{}
+  for _, entry := range m.Entries {
+    if !utils.StringIsOneOf(entry.Destination, destinations) {
+      destinations = append(destinations, entry.Destination)
+    }
+  }
+
+  text := fmt.Sprintf(reply[lang], m.Total - len(m.NotFound), strings.Join(destinations, ", "))
+  if len(m.NotFound) > 0 {
+    tags := []string{}
+    for _, tag := range m.NotFound {
+      tags = append(tags, strconv.Itoa(tag))
+    }
+    text += fmt.Sprintf(notFound[lang], strings.Join(tags, ", "))
+  }
+  return text
+}
+
+// Insert moves the animals found in the herd and adds their movement
+// records, tags that are not found are kept in NotFound.
+func (m *MovementMessage) Insert(bmv *BaseMessageValues) error {
+  m.NotFound = nil
+  for _, entry := range m.Entries {
+    for _, tag := range entry.Tags {
+      // Keep the current area of the animal up to date, the record
+      // before the update tells where the animal came from.
+      filter := bson.M{"account": bmv.Account, "tag": tag}
+      update := bson.M{"area": entry.Destination}
+      previous, err := updateRecord(bmv, "births", filter, update)
+      if err != nil {
+        return err
+      }
+      if previous == nil {
+        log.Printf("movement of unknown tag %d\n", tag)
+        m.NotFound = append(m.NotFound, tag)
+        continue
+      }
+      from, _ := previous["area"].(string)
+      birthID, _ := previous["_id"].(primitive.ObjectID)
+      recordAnimal(bmv.Account, &animal.Event{
+        Kind: animal.MOVED,
+        BirthID: birthID,
+        Tag: tag,
+        Area: entry.Destination,
+      })
+
+      document := bmv.ToMapWithDate(m.Date)
+      document = append(document, bson.E{Key: "tag", Value: tag})
+      document = append(document, bson.E{Key: "from_area", Value: from})
+      document = append(document, bson.E{Key: "to_area", Value: entry.Destination})
+      err = insertRecord(bmv, "movements", document)
+      if err != nil {
+        return err
+      }
+    }
+  }
+  return nil
+}
@@ -0,0 +1,68 @@
+package chat
+
+import (
+  "testing"
+  "posso-help/internal/area"
+  "posso-help/internal/conversation"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestMovementMessageNewArea(t *testing.T) {
+  mm := &MovementMessage{AreaParser: &area.AreaParser{}}
+  assert.True(t, mm.Parse("mover 1234 1235 para pasto norte"), "Could not parse movement")
+  assert.Equal(t, 2, mm.Total, "Total movements do not match")
+  assert.Equal(t, []int{1234, 1235}, mm.Entries[0].Tags, "Wrong tags")
+  assert.Equal(t, "pasto norte", mm.Entries[0].Destination, "Wrong destination")
+  assert.True(t, mm.Entries[0].NewArea, "Destination should be a new area")
+}
+
+func TestMovementMessageEnglish(t *testing.T) {
+  mm := &MovementMessage{}
+  assert.True(t, mm.Parse("Move 4321 to North Pasture"), "Could not parse movement")
+  assert.Equal(t, []int{4321}, mm.Entries[0].Tags, "Wrong tags")
+  assert.Equal(t, "north pasture", mm.Entries[0].Destination, "Wrong destination")
+}
+
+func TestInvalidMovementLines(t *testing.T) {
+  mm := &MovementMessage{}
+  assert.Nil(t, mm.parseAsMovementLine("mover para pasto norte"), "Movement needs a tag")
+  assert.Nil(t, mm.parseAsMovementLine("mover 1234 1235"), "Movement needs a destination")
+  assert.Nil(t, mm.parseAsMovementLine("mover 1234 pasto norte"), "Movement needs para/to")
+  assert.Nil(t, mm.parseAsMovementLine("1234 m angus"), "Birth line is not a movement")
+}
+
+func TestMovementMessageTextNotFound(t *testing.T) {
+  mm := &MovementMessage{}
+  mm.Parse("mover 1234 1235 1236 para norte")
+  mm.NotFound = []int{1236}
+  assert.Equal(t, "Zap Manejo has detected movement data. We moved 2 animals to norte.\n" +
+                  "Tags not found in the herd: 1236", mm.Text("en-US"))
+}
+
+func TestDiagnoseMovementLine(t *testing.T) {
+  mm := &MovementMessage{}
+  assert.False(t, mm.Parse("mover 1234 1235\nmover para norte"))
+  assert.Equal(t, []*LineError{{1, "mover 1234 1235", NO_DESTINATION}}, mm.GetLineErrors())
+}
+
+func TestMovementMessageConfirmation(t *testing.T) {
+  ap := &area.AreaParser{}
+  ap.AddArea("norte", "norte")
+  mm := &MovementMessage{AreaParser: ap}
+  mm.Parse("mover 1234 para norte\nmover 1235 para pasto leste")
+  kind, value, needed := mm.Confirmation()
+  assert.True(t, needed, "New areas must be confirmed")
+  assert.Equal(t, conversation.NEW_AREA, kind)
+  assert.Equal(t, "pasto leste", value)
+
+  mm.Decline("pasto leste")
+  _, _, needed = mm.Confirmation()
+  assert.False(t, needed, "A declined area is not asked again")
+  assert.Equal(t, "norte", mm.Entries[0].Destination)
+  assert.Equal(t, "unknown", mm.Entries[1].Destination, "Animals are moved to no area")
+
+  mm = &MovementMessage{AreaParser: ap}
+  mm.Parse("mover 1234 para norte")
+  _, _, needed = mm.Confirmation()
+  assert.False(t, needed, "Known areas need no confirmation")
+}
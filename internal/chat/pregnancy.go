@@ -0,0 +1,205 @@
+package chat
+
+import (
+  "fmt"
+  "log"
+  "regexp"
+  "strconv"
+  "strings"
+  "context"
+  "time"
+  "posso-help/internal/area"
+  "posso-help/internal/chat/eartag"
+  "posso-help/internal/chat/line"
+  "posso-help/internal/chat/pregnancytag"
+  "posso-help/internal/db"
+  "posso-help/internal/utils"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/mongo"
+)
+
+// Data formats for Pregnancy check data
+// "1234 prenha"
+// "1235 vazia"
+// "1236 prenha 90d"
+
+const PREGNANT = "pregnant"
+const EMPTY = "empty"
+
+// Gestation age, "90d", "90 dias" or "90 days"
+var gestationDaysRegex = regexp.MustCompile(`\b(\d{1,3})\s*(d|dias|days)\b`)
+
+type PregnancyEntry struct {
+  Id     int
+  Result string
+  Days   int
+}
+
+type PregnancyMessage struct {
+  LineErrors
+  LineClaims
+  // Time the message was received, dates are relative to it
+  Received time.Time
+  Date string
+  Entries []*PregnancyEntry
+  Area *area.Area
+  AreaParser *area.AreaParser
+  Pregnant int
+  Empty int
+}
+
+// SetDate sets the date of the message, shared by the records
+func (p *PregnancyMessage) SetDate(date string) {
+  p.Date = date
+}
+
+func (p *PregnancyMessage) GetCollection() string {
+  return "pregnancy_checks"
+}
+
+func (p *PregnancyMessage) Parse(message string) bool {
+  found := false
+  lines := strings.Split(message, "\n")
+  for index, line := range lines {
+    if date, found := p.parseDateLine(index, line, p.Received); found {
+      p.Date = date
+    }
+    if entry := p.parseAsPregnancyLine(line); entry != nil {
+      p.Entries = append(p.Entries, entry)
+      if entry.Result == PREGNANT {
+        p.Pregnant++
+      } else {
+        p.Empty++
+      }
+      p.ClaimLine(index)
+      found = true
+      continue
+    }
+    p.diagnosePregnancyLine(index, line)
+    if p.AreaParser != nil {
+      if areaName, found := p.AreaParser.ParseAsAreaLine(line); found {
+        p.Area = &area.Area{Name:areaName}
+      }
+    }
+  }
+  return found
+}
+
+func (p *PregnancyMessage) parseAsPregnancyLine(text string) (*PregnancyEntry) {
+  if isBirthLine(text) {
+    return nil
+  }
+  text = utils.SanitizeLine(text)
+  parser := line.NewLineParser().
+    MustHave("tag", eartag.New()).
+    MustHave("result", pregnancytag.New())
+  if !parser.Parse(text) {
+    return nil
+  }
+
+  entry := &PregnancyEntry{
+    Id: parser.ValueAsInt("tag"),
+    Result: parser.Value("result"),
+  }
+  if matches := gestationDaysRegex.FindStringSubmatch(text); len(matches) == 3 {
+    entry.Days, _ = strconv.Atoi(matches[1])
+  }
+  return entry
+}
+
+// Words at least this similar to a result are taken as a misspelled
+// result, see utils.Similarity.
+const RESULT_SIMILARITY = 0.8
+
+// diagnosePregnancyLine adds a line error when the line looks like a
+// pregnancy check, "{tag} {result}" or "{tag} ... 90d", but its result
+// is not known.
+func (p *PregnancyMessage) diagnosePregnancyLine(index int, text string) {
+  words, found := startsWithTag(text)
+  if !found || len(words) < 2 || isBirthLine(text) {
+    return
+  }
+  if looksLikePregnancyResult(words[1]) || gestationDaysRegex.MatchString(utils.SanitizeLine(text)) {
+    p.AddLineError(index, text, UNKNOWN_RESULT)
+  }
+}
+
+// isBirthLine reports whether the tag of the line is followed by a sex
+// and a breed, "1234 m angus prenha", so the line is left to the birth
+// parser and its sex and breed are not dropped.
+func isBirthLine(text string) bool {
+  words, found := startsWithTag(text)
+  return found && len(words) > 2 && utils.StringIsOneOf(words[1], SEXES) && !looksLikePregnancyResult(words[2])
+}
+
+// looksLikePregnancyResult reports whether the word is, or is similar
+// to, a pregnancy check result
+func looksLikePregnancyResult(word string) bool {
+  for _, result := range append(pregnancytag.PREGNANT_WORDS, pregnancytag.EMPTY_WORDS...) {
+    if utils.Similarity(word, result) >= RESULT_SIMILARITY {
+      return true
+    }
+  }
+  return false
+}
+
+func (p *PregnancyMessage) Text(lang string) string {
+  reply := map[string]string {
+    "en-US" : "Zap Manejo has detected pregnancy check data. " +
+              "We added %d checks: %d pregnant and %d empty (%.0f%% pregnancy rate).",
+    "pt-BR" : "Zap Manejo detectou dados de diagnóstico de prenhez. " +
+              "Adicionamos %d diagnósticos: %d prenhas e %d vazias (%.0f%% de prenhez).",
+  }
+
+  total := p.Pregnant + p.Empty
+  rate := 0.0
+  if total > 0 {
+    rate = float64(p.Pregnant) * 100 / float64(total)
+  }
+
+  if lang == "pt-BR" ||  lang == "en-US" {
+    return fmt.Sprintf(reply[lang], total, p.Pregnant, p.Empty, rate)
+  }
+
+  log.Printf("Unsupported or Unknown Language: (%s)", lang)
+  return fmt.Sprintf(reply["pt-BR"], total, p.Pregnant, p.Empty, rate)
+}
+
+func (p *PregnancyMessage) Insert(bmv *BaseMessageValues) error {
+  births := db.GetCollection("births")
+  for _, entry := range p.Entries {
+    document := bmv.ToMapWithDate(p.Date)
+    document = append(document, bson.E{Key: "tag", Value: entry.Id})
+    document = append(document, bson.E{Key: "result", Value: entry.Result})
+    if entry.Days > 0 {
+      document = append(document, bson.E{Key: "days", Value: entry.Days})
+    }
+
+    // Link the check to the dam in the births collection, and use the
+    // area of the dam when no area was sent with the message.
+    areaName := "unknown"
+    dam := bson.M{}
+    filter := bson.M{"account": bmv.Account, "tag": entry.Id}
+    err := births.FindOne(context.TODO(), filter).Decode(&dam)
+    if err == nil {
+      document = append(document, bson.E{Key: "birth_id", Value: dam["_id"]})
+      if damArea, ok := dam["area"].(string); ok && damArea != "" {
+        areaName = damArea
+      }
+    } else if err != mongo.ErrNoDocuments {
+      return err
+    } else {
+      log.Printf("pregnancy check of unknown dam %d\n", entry.Id)
+    }
+    if p.Area != nil {
+      areaName = p.Area.Name
+    }
+    document = append(document, bson.E{Key: "area", Value: areaName})
+
+    err = insertRecord(bmv, "pregnancy_checks", document)
+    if err != nil {
+      return err
+    }
+  }
+  return nil
+}
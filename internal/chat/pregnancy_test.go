@@ -0,0 +1,46 @@
+package chat
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestPregnancyMessage(t *testing.T) {
+  input := `1234 prenha
+1235 vazia
+1236 prenha 90d
+1237 Pregnant 120 days`
+
+  pm := &PregnancyMessage{}
+  assert.True(t, pm.Parse(input), "Could not parse pregnancy message")
+  assert.Equal(t, 4, len(pm.Entries), "Should have 4 entries")
+  assert.Equal(t, 3, pm.Pregnant, "Pregnant count does not match")
+  assert.Equal(t, 1, pm.Empty, "Empty count does not match")
+
+  assert.Equal(t, 1234, pm.Entries[0].Id, "Wrong tag")
+  assert.Equal(t, PREGNANT, pm.Entries[0].Result, "Wrong result")
+  assert.Equal(t, 0, pm.Entries[0].Days, "Days should not be set")
+  assert.Equal(t, EMPTY, pm.Entries[1].Result, "Wrong result")
+  assert.Equal(t, 90, pm.Entries[2].Days, "Wrong gestation days")
+  assert.Equal(t, 120, pm.Entries[3].Days, "Wrong gestation days")
+
+  assert.Contains(t, pm.Text("en-US"), "3 pregnant and 1 empty (75% pregnancy rate)")
+}
+
+func TestInvalidPregnancyLines(t *testing.T) {
+  pm := &PregnancyMessage{}
+  assert.Nil(t, pm.parseAsPregnancyLine("prenha"), "Pregnancy check needs a tag")
+  assert.Nil(t, pm.parseAsPregnancyLine("1234 m angus"), "Birth line is not a pregnancy check")
+  assert.Nil(t, pm.parseAsPregnancyLine("1234 morreu"), "Death line is not a pregnancy check")
+  assert.Nil(t, pm.parseAsPregnancyLine("1234 m angus prenha"), "Birth line with a result word is not a pregnancy check")
+  assert.NotNil(t, pm.parseAsPregnancyLine("1234 f prenha"), "A sex before the result is not a birth line")
+}
+
+func TestDiagnosePregnancyLine(t *testing.T) {
+  pm := &PregnancyMessage{}
+  assert.False(t, pm.Parse("1234 prenhx\n1235 duvidosa 60d\n1236 raio\n1237 m nelore"))
+  assert.Equal(t, []*LineError{
+    {1, "1234 prenhx", UNKNOWN_RESULT},
+    {2, "1235 duvidosa 60d", UNKNOWN_RESULT},
+  }, pm.GetLineErrors())
+}
@@ -0,0 +1,16 @@
+package pregnancytag
+
+import (
+  "posso-help/internal/chat/tag"
+)
+
+// Words of each pregnancy check result
+var PREGNANT_WORDS = []string{"prenha", "prenhe", "pregnant", "positiva"}
+var EMPTY_WORDS = []string{"vazia", "vazio", "empty", "open", "negativa"}
+
+func New() tag.Tag {
+  return tag.NewStringSet(
+    tag.NewString("pregnant", PREGNANT_WORDS),
+    tag.NewString("empty",    EMPTY_WORDS),
+  )
+}
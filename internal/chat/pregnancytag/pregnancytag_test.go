@@ -0,0 +1,37 @@
+package pregnancytag
+
+import (
+  "fmt"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+type TestCase struct {
+  Input string
+  Found bool 
+  Value string
+  ValueInt int
+}
+
+func TestPregnancyTag(t *testing.T) {
+  pregnancy := New()
+  tests := []TestCase{
+    {"1234 prenha",     true,  "pregnant", 0},
+    {"1236 prenha 90d", true,  "pregnant", 0},
+    {"1234 pregnant",   true,  "pregnant", 0},
+    {"1235 vazia",      true,  "empty",    0},
+    {"1235 open",       true,  "empty",    0},
+    {"1235 m angus",    false, "",         0},
+  }
+  for index, test := range tests {
+    found := pregnancy.Parse(test.Input)
+    assert.Equal(t, test.Found, found, 
+                 fmt.Sprintf("test: %d", index))
+    if found {
+      assert.Equal(t, test.Value, pregnancy.Value(), 
+                   fmt.Sprintf("test: %d", index))
+    }
+    assert.Equal(t, test.ValueInt, pregnancy.ValueAsInt(), 
+                 fmt.Sprintf("test: %d", index))
+  }
+}
@@ -4,9 +4,8 @@ import (
   "fmt"
   "log"
   "strings"
-  "context"
+  "time"
   "posso-help/internal/area"  
-  "posso-help/internal/db"  
   "posso-help/internal/date"  
   "posso-help/internal/utils"  
   "go.mongodb.org/mongo-driver/bson"
@@ -27,8 +26,13 @@ type RainEntry struct {
 }
 
 type RainMessage struct {
+  LineErrors
+  LineClaims
+  // Time the message was received, dates are relative to it
+  Received time.Time
   Entries []*RainEntry
   Area *area.Area
+  AreaParser *area.AreaParser
   Total int
 }
 
@@ -40,27 +44,44 @@ func (b *RainMessage) GetCollection() string {
 func (r *RainMessage) Parse(message string) bool {
   found := false
   lines := strings.Split(message, "\n")
-  for _, line := range lines {
+  for index, line := range lines {
+    if date.HasBadDate(line) {
+      r.AddLineError(index, line, BAD_DATE)
+      continue
+    }
     if entry := r.parseRainLine(line); entry != nil {
       r.Entries = append(r.Entries, entry)
       r.Total += entry.Amount
+      r.ClaimLine(index)
       found = true
+      continue
+    }
+    if r.AreaParser != nil {
+      if areaName, found := r.AreaParser.ParseAsAreaLine(line); found {
+        r.Area = &area.Area{Name:areaName}
+      }
     }
   }
   return found 
 }
 
 func (r *RainMessage) parseRainLine(line string) (*RainEntry) {
-  var day, month int
   var rainfall int // in millimeters
   line = utils.SanitizeLine(line)
 
+  // The line starts with the date, dd/mm, dd/mm/yy or dd/mm/yyyy
+  first, rest, _ := strings.Cut(line, " ")
+  rainDate, found := date.ParseDate(first, r.Received)
+  if !found {
+    return nil
+  }
+
   // Support both 15mm and 15 mm (with space)
-  line = strings.Replace(line, "mm", " mm", 1)
-  n, err := fmt.Sscanf(line, "%d/%d %d mm", &day, &month, &rainfall)
-  if err == nil && n == 3 {
+  rest = strings.Replace(rest, "mm", " mm", 1)
+  n, err := fmt.Sscanf(rest, "%d mm", &rainfall)
+  if err == nil && n == 1 {
     return &RainEntry{
-      Date: date.MonthDayToUTC(month, day),
+      Date: rainDate,
       Amount: rainfall,
     }
   }
@@ -84,12 +105,14 @@ func (r *RainMessage) Text(lang string) string {
 }
 
 func (b *RainMessage) Insert(bmv *BaseMessageValues) error {
-  rains := db.GetCollection("rain")
   for _, rain := range b.Entries {
     document := bmv.ToMap()
     document = append(document, bson.E{Key: "amount", Value: rain.Amount})
     document = append(document, bson.E{Key: "date", Value: rain.Date})
-    _, err := rains.InsertOne(context.TODO(), document)
+    if b.Area != nil {
+      document = append(document, bson.E{Key: "area", Value: b.Area.Name})
+    }
+    err := insertRecord(bmv, "rain", document)
     if err != nil {
       return err
     }
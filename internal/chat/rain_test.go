@@ -2,6 +2,8 @@ package chat
 
 import (
   "testing"
+  "time"
+  "posso-help/internal/area"
   "github.com/stretchr/testify/assert"
 )
 
@@ -12,3 +14,21 @@ func TestRainMessage(t *testing.T) {
   assert.Equal(t, len(rm.Entries), 3, "Wrong number of rain entries")
   assert.Equal(t, rm.Total, 67, "Wrong rain total")
 }
+
+func TestRainMessageYear(t *testing.T) {
+  input := "30/12 12mm\n02/01 8 mm\n15/06/2025 30mm"
+  rm := &RainMessage{Received: time.Date(2026, time.January, 2, 9, 0, 0, 0, time.UTC)}
+  assert.True(t, rm.Parse(input), "Could not parse rain message")
+  assert.Equal(t, 3, len(rm.Entries), "Wrong number of rain entries")
+  assert.Equal(t, "2025-12-30T00:00:00Z", rm.Entries[0].Date, "30/12 sent in January is from last year")
+  assert.Equal(t, "2026-01-02T00:00:00Z", rm.Entries[1].Date)
+  assert.Equal(t, "2025-06-15T00:00:00Z", rm.Entries[2].Date)
+}
+
+func TestRainMessageArea(t *testing.T) {
+  ap := &area.AreaParser{}
+  ap.AddArea("norte", "norte")
+  rm := &RainMessage{AreaParser: ap}
+  assert.True(t, rm.Parse("Norte\n02/04 40mm"), "Could not parse rain message")
+  assert.Equal(t, "norte", rm.Area.Name, "Area does not match")
+}
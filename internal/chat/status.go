@@ -0,0 +1,268 @@
+package chat
+
+import (
+  "fmt"
+  "log"
+  "sort"
+  "time"
+  "strconv"
+  "strings"
+  "context"
+  "posso-help/internal/date"
+  "posso-help/internal/db"
+  "posso-help/internal/utils"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/mongo"
+  "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Data formats for Status queries
+// "status 1234"
+// "ficha 1234"
+
+// Keywords that ask for the status of an animal (English and Portuguese)
+var STATUS_KEYWORDS = []string{"status", "ficha"}
+
+// Collections holding events of an animal, linked by tag
+var EVENT_COLLECTIONS = []string{
+  "weights", "treatments", "movements", "pregnancy_checks", "inseminations",
+  DeathsCollection,
+}
+
+// How many of the latest events are listed in the status reply
+const STATUS_EVENTS = 5
+
+type AnimalEvent struct {
+  Collection string
+  Date string
+  Record bson.M
+}
+
+type StatusMessage struct {
+  // Time the message was received, dates are shown in its time zone
+  Received time.Time
+  Tag int
+  Animal bson.M
+  // Deaths record of a dead animal, with the cause and the note
+  Death bson.M
+  Events []*AnimalEvent
+}
+
+func (s *StatusMessage) GetCollection() string {
+  return "status"
+}
+
+func (s *StatusMessage) Parse(message string) bool {
+  words := strings.Fields(utils.SanitizeLine(message))
+  if len(words) != 2 || !utils.StringIsOneOf(words[0], STATUS_KEYWORDS) {
+    return false
+  }
+  tag, err := strconv.Atoi(words[1])
+  if err != nil || tag <= 0 {
+    return false
+  }
+  s.Tag = tag
+  return true
+}
+
+// Insert does not write anything, it reads the animal and its latest
+// events so Text can reply with the summary.
+func (s *StatusMessage) Insert(bmv *BaseMessageValues) error {
+  filter := bson.M{"account": bmv.Account, "tag": s.Tag}
+  animal := bson.M{}
+  err := db.GetCollection("births").FindOne(context.TODO(), filter).Decode(&animal)
+  if err == mongo.ErrNoDocuments {
+    log.Printf("status of unknown tag %d\n", s.Tag)
+    return nil
+  }
+  if err != nil {
+    return err
+  }
+  s.Animal = animal
+  if animal["status"] == DEAD {
+    err = s.findDeath(bmv.Account)
+    if err != nil {
+      return err
+    }
+  }
+
+  opts := options.Find().
+    SetSort(bson.D{{Key: "date", Value: -1}}).
+    SetLimit(STATUS_EVENTS)
+  for _, collection := range EVENT_COLLECTIONS {
+    cursor, err := db.GetCollection(collection).Find(context.TODO(), filter, opts)
+    if err != nil {
+      return err
+    }
+    records := []bson.M{}
+    err = cursor.All(context.TODO(), &records)
+    if err != nil {
+      return err
+    }
+    for _, record := range records {
+      date, _ := record["date"].(string)
+      s.Events = append(s.Events, &AnimalEvent{collection, date, record})
+    }
+  }
+  s.sortEvents()
+  return nil
+}
+
+// findDeath reads the deaths record of the animal, found by its births
+// record, for the cause of the death.
+func (s *StatusMessage) findDeath(account string) error {
+  filter := bson.M{"account": account, "birth_id": s.Animal["_id"]}
+  opts := options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}})
+  death := bson.M{}
+  err := db.GetCollection(DeathsCollection).FindOne(context.TODO(), filter, opts).Decode(&death)
+  if err == mongo.ErrNoDocuments {
+    return nil
+  }
+  if err != nil {
+    return err
+  }
+  s.Death = death
+  return nil
+}
+
+// sortEvents keeps the STATUS_EVENTS latest events, newest first
+func (s *StatusMessage) sortEvents() {
+  sort.SliceStable(s.Events, func(i, j int) bool {
+    return date.Before(s.Events[j].Date, s.Events[i].Date)
+  })
+  if len(s.Events) > STATUS_EVENTS {
+    s.Events = s.Events[:STATUS_EVENTS]
+  }
+}
+
+func (s *StatusMessage) Text(lang string) string {
+  notFound := map[string]string {
+    "en-US" : "Zap Manejo could not find animal %d in the herd.",
+    "pt-BR" : "Zap Manejo não encontrou o animal %d no rebanho.",
+  }
+  summary := map[string]string {
+    "en-US" : "Zap Manejo animal %d:\nBirth: %s\nSex: %s\nBreed: %s\nArea: %s",
+    "pt-BR" : "Zap Manejo animal %d:\nNascimento: %s\nSexo: %s\nRaça: %s\nÁrea: %s",
+  }
+  dam := map[string]string {
+    "en-US" : "\nDam: %v",
+    "pt-BR" : "\nMãe: %v",
+  }
+  sire := map[string]string {
+    "en-US" : "\nSire: %v",
+    "pt-BR" : "\nPai: %v",
+  }
+  cause := map[string]string {
+    "en-US" : "\nDeath: %v",
+    "pt-BR" : "\nÓbito: %v",
+  }
+  events := map[string]string {
+    "en-US" : "\nLatest events:",
+    "pt-BR" : "\nÚltimos eventos:",
+  }
+
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+
+  if s.Animal == nil {
+    return fmt.Sprintf(notFound[lang], s.Tag)
+  }
+
+  loc := s.Received.Location()
+
+  text := fmt.Sprintf(summary[lang], s.Tag,
+                      shortDate(s.Animal["date"], loc),
+                      stringValue(s.Animal["sex"]),
+                      stringValue(s.Animal["breed"]),
+                      stringValue(s.Animal["area"]))
+  if value, ok := s.Animal["dam"]; ok && fmt.Sprintf("%v", value) != "0" {
+    text += fmt.Sprintf(dam[lang], value)
+  }
+  if value, ok := s.Animal["sire"]; ok && value != "" {
+    text += fmt.Sprintf(sire[lang], value)
+  }
+  if s.Animal["status"] == DEAD {
+    death := shortDate(s.Animal["death_date"], loc)
+    if s.Death != nil {
+      death += " " + stringValue(s.Death["cause"])
+      if note, ok := s.Death["note"].(string); ok && note != "" {
+        death += fmt.Sprintf(" (%s)", note)
+      }
+    }
+    text += fmt.Sprintf(cause[lang], death)
+  } else if value, ok := s.Animal["cause"]; ok {
+    // Deaths reported before the deaths collection
+    text += fmt.Sprintf(cause[lang], value)
+  }
+  if len(s.Events) > 0 {
+    text += events[lang]
+    for _, event := range s.Events {
+      text += fmt.Sprintf("\n%s %s", shortDate(event.Date, loc), describeEvent(lang, event))
+    }
+  }
+  return text
+}
+
+// describeEvent returns a short localized description of the event
+func describeEvent(lang string, event *AnimalEvent) string {
+  descriptions := map[string]map[string]string {
+    "weights" : {
+      "en-US" : "weight %v kg",
+      "pt-BR" : "peso %v kg",
+    },
+    "treatments" : {
+      "en-US" : "treatment %v",
+      "pt-BR" : "tratamento %v",
+    },
+    "movements" : {
+      "en-US" : "moved to %v",
+      "pt-BR" : "movido para %v",
+    },
+    "pregnancy_checks" : {
+      "en-US" : "pregnancy check %v",
+      "pt-BR" : "diagnóstico de prenhez %v",
+    },
+    "inseminations" : {
+      "en-US" : "insemination %v",
+      "pt-BR" : "inseminação %v",
+    },
+    DeathsCollection : {
+      "en-US" : "death %v",
+      "pt-BR" : "óbito %v",
+    },
+  }
+  fields := map[string]string {
+    "weights" : "weight",
+    "treatments" : "product",
+    "movements" : "to_area",
+    "pregnancy_checks" : "result",
+    "inseminations" : "sire",
+    DeathsCollection : "cause",
+  }
+
+  description, ok := descriptions[event.Collection]
+  if !ok {
+    return event.Collection
+  }
+  return fmt.Sprintf(description[lang], stringValue(event.Record[fields[event.Collection]]))
+}
+
+// stringValue returns the value as text, or "-" when it is missing
+func stringValue(value interface{}) string {
+  if value == nil {
+    return "-"
+  }
+  text := fmt.Sprintf("%v", value)
+  if text == "" {
+    return "-"
+  }
+  return text
+}
+
+// shortDate returns the yyyy-mm-dd day of an RFC3339 date in the time
+// zone
+func shortDate(value interface{}, loc *time.Location) string {
+  return date.Day(stringValue(value), loc)
+}
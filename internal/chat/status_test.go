@@ -0,0 +1,71 @@
+package chat
+
+import (
+  "fmt"
+  "testing"
+  "time"
+  "posso-help/internal/date"
+  "github.com/stretchr/testify/assert"
+  "go.mongodb.org/mongo-driver/bson"
+)
+
+func TestStatusMessageParse(t *testing.T) {
+  assert.True(t, (&StatusMessage{}).Parse("status 1234"), "Could not parse status")
+  assert.True(t, (&StatusMessage{}).Parse("  Ficha 1234 "), "Could not parse ficha")
+  assert.False(t, (&StatusMessage{}).Parse("status"), "Status needs a tag")
+  assert.False(t, (&StatusMessage{}).Parse("status abc"), "Status needs a numeric tag")
+  assert.False(t, (&StatusMessage{}).Parse("1234 m angus"), "Birth is not a status query")
+}
+
+func TestStatusMessageText(t *testing.T) {
+  sm := &StatusMessage{Tag: 1234}
+  assert.Equal(t, "Zap Manejo could not find animal 1234 in the herd.", sm.Text("en-US"))
+
+  sm.Animal = bson.M{
+    "tag": 1234, "date": "2025-01-15T00:00:00Z", "sex": "f",
+    "breed": "nelore", "area": "pasto norte", "dam": 555, "sire": "touro1", "cause": "morreu",
+  }
+  sm.Events = []*AnimalEvent{
+    {"weights", "2025-02-01T00:00:00Z", bson.M{"weight": 120.5}},
+    {"movements", "2025-03-01T00:00:00Z", bson.M{"to_area": "pasto sul"}},
+  }
+  sm.sortEvents()
+  text := sm.Text("pt-BR")
+  assert.Contains(t, text, "Nascimento: 2025-01-15")
+  assert.Contains(t, text, "Raça: nelore")
+  assert.Contains(t, text, "Mãe: 555")
+  assert.Contains(t, text, "Pai: touro1")
+  assert.Contains(t, text, "Óbito: morreu")
+  assert.Contains(t, text, "2025-03-01 movido para pasto sul\n2025-02-01 peso 120.5 kg")
+
+  sm.Animal = bson.M{"tag": 1234, "status": DEAD, "death_date": "2025-04-10T00:00:00-03:00"}
+  sm.Death = bson.M{"cause": "picada de cobra", "note": "perto do rio"}
+  sm.Events = []*AnimalEvent{
+    {DeathsCollection, "2025-04-10T00:00:00-03:00", bson.M{"cause": "morreu"}},
+  }
+  text = sm.Text("en-US")
+  assert.Contains(t, text, "Death: 2025-04-10 picada de cobra (perto do rio)")
+  assert.Contains(t, text, "2025-04-10 death morreu")
+}
+
+func TestStatusSortEvents(t *testing.T) {
+  sm := &StatusMessage{}
+  for day := 1; day <= 6; day++ {
+    date := fmt.Sprintf("2025-02-%02dT00:00:00Z", day)
+    sm.Events = append(sm.Events, &AnimalEvent{"weights", date, bson.M{}})
+  }
+  sm.Events = append(sm.Events, &AnimalEvent{"movements", "2025-02-06T00:00:00-03:00", bson.M{}})
+  sm.sortEvents()
+  assert.Equal(t, STATUS_EVENTS, len(sm.Events), "Only the latest events are kept")
+  assert.Equal(t, "2025-02-06T00:00:00-03:00", sm.Events[0].Date, "Dates are compared as times")
+  assert.Equal(t, "2025-02-06T00:00:00Z", sm.Events[1].Date)
+  assert.Equal(t, "2025-02-03T00:00:00Z", sm.Events[4].Date)
+}
+
+func TestStatusMessageTimezone(t *testing.T) {
+  tokyo := time.FixedZone("JST", 9 * 60 * 60)
+  sm := &StatusMessage{Received: time.Date(2025, time.March, 1, 9, 0, 0, 0, tokyo), Tag: 1234}
+  // Midnight of the 15th in Tokyo is the 14th in UTC
+  sm.Animal = bson.M{"tag": 1234, "date": date.DateIn(2025, 1, 15, tokyo), "sex": "f"}
+  assert.Contains(t, sm.Text("en-US"), "Birth: 2025-01-15", "Dates are shown in the time zone of the team")
+}
@@ -0,0 +1,281 @@
+package chat
+
+import (
+  "fmt"
+  "log"
+  "sort"
+  "time"
+  "strings"
+  "context"
+  "posso-help/internal/date"
+  "posso-help/internal/db"
+  "posso-help/internal/utils"
+  "go.mongodb.org/mongo-driver/bson"
+)
+
+// Data formats for Summary queries
+// "resumo"
+// "summary"
+
+// Keywords that ask for the herd summary (English and Portuguese)
+var SUMMARY_KEYWORDS = []string{"resumo", "summary"}
+
+// AreaSummary holds the month to date counts of an area
+type AreaSummary struct {
+  Births int
+  BirthsBySex map[string]int
+  BirthsByBreed map[string]int
+  Deaths int
+  DeathsByCause map[string]int
+  Rain float64
+}
+
+type SummaryMessage struct {
+  // Time the message was received, the month is the month it was
+  // received in the time zone of the team
+  Received time.Time
+  Month string
+  // Counts by area name, records without an area are under NO_AREA
+  Areas map[string]*AreaSummary
+  Temperatures []float64
+}
+
+// Area of the summary for records without an area
+const NO_AREA = ""
+
+func (s *SummaryMessage) GetCollection() string {
+  return "summary"
+}
+
+func (s *SummaryMessage) Parse(message string) bool {
+  return utils.StringIsOneOf(utils.SanitizeLine(message), SUMMARY_KEYWORDS)
+}
+
+// readMonth returns the records of the collection for the account
+// dated from the start of the month on.
+func (s *SummaryMessage) readMonth(collection, account, monthStart string) ([]bson.M, error) {
+  filter := bson.M{"account": account, "date": bson.M{"$gte": monthStart}}
+  cursor, err := db.GetCollection(collection).Find(context.TODO(), filter)
+  if err != nil {
+    return nil, err
+  }
+  records := []bson.M{}
+  err = cursor.All(context.TODO(), &records)
+  return records, err
+}
+
+// Insert does not write anything, it aggregates the month to date
+// records of the account so Text can reply with the summary.
+func (s *SummaryMessage) Insert(bmv *BaseMessageValues) error {
+  now := s.Received
+  if now.IsZero() {
+    now = time.Now()
+  }
+  s.Month = now.Format("01/2006")
+  monthStart := date.DateIn(now.Year(), int(now.Month()), 1, now.Location())
+
+  births, err := s.readMonth("births", bmv.Account, monthStart)
+  if err != nil {
+    return err
+  }
+  deaths, err := s.readMonth(DeathsCollection, bmv.Account, monthStart)
+  if err != nil {
+    return err
+  }
+  rains, err := s.readMonth("rain", bmv.Account, monthStart)
+  if err != nil {
+    return err
+  }
+  temperatures, err := s.readMonth("temperature", bmv.Account, monthStart)
+  if err != nil {
+    return err
+  }
+  s.aggregate(births, deaths, rains, temperatures)
+  return nil
+}
+
+func (s *SummaryMessage) aggregate(births, deaths, rains, temperatures []bson.M) {
+  s.Areas = map[string]*AreaSummary{}
+
+  for _, birth := range births {
+    summary := s.area(birth)
+    summary.Births++
+    summary.BirthsBySex[stringValue(birth["sex"])]++
+    summary.BirthsByBreed[stringValue(birth["breed"])]++
+  }
+  for _, death := range deaths {
+    summary := s.area(death)
+    summary.Deaths++
+    summary.DeathsByCause[stringValue(death["cause"])]++
+  }
+  for _, rain := range rains {
+    s.area(rain).Rain += numberValue(rain["amount"])
+  }
+  for _, temperature := range temperatures {
+    s.Temperatures = append(s.Temperatures, numberValue(temperature["temperature"]))
+  }
+}
+
+// area returns the summary of the area of the record
+func (s *SummaryMessage) area(record bson.M) *AreaSummary {
+  name, _ := record["area"].(string)
+  if name == "unknown" {
+    name = NO_AREA
+  }
+  summary, ok := s.Areas[name]
+  if !ok {
+    summary = &AreaSummary{
+      BirthsBySex: map[string]int{},
+      BirthsByBreed: map[string]int{},
+      DeathsByCause: map[string]int{},
+    }
+    s.Areas[name] = summary
+  }
+  return summary
+}
+
+func (s *SummaryMessage) Text(lang string) string {
+  header := map[string]string {
+    "en-US" : "Zap Manejo summary %s",
+    "pt-BR" : "Zap Manejo resumo %s",
+  }
+  births := map[string]string {
+    "en-US" : "\nBirths: %d",
+    "pt-BR" : "\nNascimentos: %d",
+  }
+  deaths := map[string]string {
+    "en-US" : "\nDeaths: %d",
+    "pt-BR" : "\nÓbitos: %d",
+  }
+  rain := map[string]string {
+    "en-US" : "\nRain: %.0f mm",
+    "pt-BR" : "\nChuva: %.0f mm",
+  }
+  temperature := map[string]string {
+    "en-US" : "\nTemperature: min %.0f C, max %.0f C, average %.1f C",
+    "pt-BR" : "\nTemperatura: mín %.0f C, máx %.0f C, média %.1f C",
+  }
+  areaHeader := map[string]string {
+    "en-US" : "\n\nArea %s",
+    "pt-BR" : "\n\nÁrea %s",
+  }
+  noArea := map[string]string {
+    "en-US" : "\n\nNo area",
+    "pt-BR" : "\n\nSem área",
+  }
+  sex := map[string]string {
+    "en-US" : "\n  Sex: %s",
+    "pt-BR" : "\n  Sexo: %s",
+  }
+  breed := map[string]string {
+    "en-US" : "\n  Breed: %s",
+    "pt-BR" : "\n  Raça: %s",
+  }
+  cause := map[string]string {
+    "en-US" : "\n  Cause: %s",
+    "pt-BR" : "\n  Causa: %s",
+  }
+
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+
+  names := []string{}
+  total := &AreaSummary{}
+  for name, summary := range s.Areas {
+    names = append(names, name)
+    total.Births += summary.Births
+    total.Deaths += summary.Deaths
+    total.Rain += summary.Rain
+  }
+  // Records without an area are listed last
+  sort.Slice(names, func(i, j int) bool {
+    if names[i] == NO_AREA || names[j] == NO_AREA {
+      return names[j] == NO_AREA && names[i] != NO_AREA
+    }
+    return names[i] < names[j]
+  })
+
+  text := fmt.Sprintf(header[lang], s.Month)
+  text += fmt.Sprintf(births[lang], total.Births)
+  text += fmt.Sprintf(deaths[lang], total.Deaths)
+  text += fmt.Sprintf(rain[lang], total.Rain)
+  if len(s.Temperatures) > 0 {
+    min, max, sum := s.Temperatures[0], s.Temperatures[0], 0.0
+    for _, value := range s.Temperatures {
+      if value < min {
+        min = value
+      }
+      if value > max {
+        max = value
+      }
+      sum += value
+    }
+    text += fmt.Sprintf(temperature[lang], min, max, sum / float64(len(s.Temperatures)))
+  }
+
+  for _, name := range names {
+    summary := s.Areas[name]
+    if name == NO_AREA {
+      text += noArea[lang]
+    } else {
+      text += fmt.Sprintf(areaHeader[lang], name)
+    }
+    text += fmt.Sprintf(births[lang], summary.Births)
+    if summary.Births > 0 {
+      text += fmt.Sprintf(sex[lang], formatInline(summary.BirthsBySex))
+      text += fmt.Sprintf(breed[lang], formatInline(summary.BirthsByBreed))
+    }
+    text += fmt.Sprintf(deaths[lang], summary.Deaths)
+    if summary.Deaths > 0 {
+      text += fmt.Sprintf(cause[lang], formatInline(summary.DeathsByCause))
+    }
+    text += fmt.Sprintf(rain[lang], summary.Rain)
+  }
+  return text
+}
+
+// formatCounts returns the counts as "  name: count" lines, sorted by name
+func formatCounts(counts map[string]int) string {
+  lines := []string{}
+  for _, name := range sortedNames(counts) {
+    lines = append(lines, fmt.Sprintf("\n  %s: %d", name, counts[name]))
+  }
+  return strings.Join(lines, "")
+}
+
+// formatInline returns the counts as "name count" items of a single
+// line, sorted by name
+func formatInline(counts map[string]int) string {
+  items := []string{}
+  for _, name := range sortedNames(counts) {
+    items = append(items, fmt.Sprintf("%s %d", name, counts[name]))
+  }
+  return strings.Join(items, ", ")
+}
+
+func sortedNames(counts map[string]int) []string {
+  names := []string{}
+  for name := range counts {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  return names
+}
+
+// numberValue returns the numeric value of a record field, which can
+// be stored as any of the bson number types.
+func numberValue(value interface{}) float64 {
+  switch number := value.(type) {
+  case int:
+    return float64(number)
+  case int32:
+    return float64(number)
+  case int64:
+    return float64(number)
+  case float64:
+    return number
+  }
+  return 0
+}
@@ -0,0 +1,52 @@
+package chat
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+  "go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSummaryMessageParse(t *testing.T) {
+  assert.True(t, (&SummaryMessage{}).Parse("Resumo"), "Could not parse resumo")
+  assert.True(t, (&SummaryMessage{}).Parse(" summary "), "Could not parse summary")
+  assert.False(t, (&SummaryMessage{}).Parse("resumo 1234"), "Summary takes no arguments")
+}
+
+func TestSummaryMessageText(t *testing.T) {
+  births := []bson.M{
+    {"sex": "f", "breed": "nelore", "area": "norte"},
+    {"sex": "m", "breed": "nelore", "area": "norte"},
+    {"sex": "f", "breed": "angus", "area": "sul", "status": "dead"},
+  }
+  deaths := []bson.M{{"tag": 1236, "cause": "natimorto", "area": "sul"}}
+  rains := []bson.M{{"amount": int32(12), "area": "norte"}, {"amount": int64(30)}}
+  temperatures := []bson.M{{"temperature": int32(28)}, {"temperature": int32(34)}}
+
+  sm := &SummaryMessage{Month: "03/2025"}
+  sm.aggregate(births, deaths, rains, temperatures)
+  assert.Equal(t, "Zap Manejo summary 03/2025\n" +
+                  "Births: 3\n" +
+                  "Deaths: 1\n" +
+                  "Rain: 42 mm\n" +
+                  "Temperature: min 28 C, max 34 C, average 31.0 C\n" +
+                  "\n" +
+                  "Area norte\n" +
+                  "Births: 2\n" +
+                  "  Sex: f 1, m 1\n" +
+                  "  Breed: nelore 2\n" +
+                  "Deaths: 0\n" +
+                  "Rain: 12 mm\n" +
+                  "\n" +
+                  "Area sul\n" +
+                  "Births: 1\n" +
+                  "  Sex: f 1\n" +
+                  "  Breed: angus 1\n" +
+                  "Deaths: 1\n" +
+                  "  Cause: natimorto 1\n" +
+                  "Rain: 0 mm\n" +
+                  "\n" +
+                  "No area\n" +
+                  "Births: 0\n" +
+                  "Deaths: 0\n" +
+                  "Rain: 30 mm", sm.Text("en-US"))
+}
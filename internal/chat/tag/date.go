@@ -4,12 +4,17 @@ import (
   "fmt"
   "log"
   "regexp"
+  "strconv"
   "strings"
 	"time"
+  "posso-help/internal/date"
 )
 
 type Date struct {
   value string
+  // Time the text was written, the year of dd/mm dates is inferred
+  // from it.  Now when zero.
+  Reference time.Time
 }
 
 func NewDate() *Date {
@@ -19,6 +24,7 @@ func NewDate() *Date {
 // Parse accepts a string as input and returns a date if one
 // is found.  The date is returned as yyyy-mm-dd
 // Example:  dd/mm => yyyy-mm-dd
+//           dd/mm/yy or dd/mm/yyyy => yyyy-mm-dd
 func (d *Date) Parse(text string) bool {
   d.value = ""
   dateFound := d.findDate(text)
@@ -26,6 +32,11 @@ func (d *Date) Parse(text string) bool {
     d.value = dateFound
     return true
   }
+  dateFound = d.findDateLong(text)
+  if dateFound != "" {
+    d.value = dateFound
+    return true
+  }
   dateFound = d.findDateShort(text)
   if dateFound != "" {
     d.value = dateFound
@@ -40,11 +51,28 @@ func (d *Date) findDateShort(text string) string {
   if len(matches) != 4 {
     return ""
   }
-  year := time.Now().Year()
+  month, _ := strconv.Atoi(matches[3])
+  day, _ := strconv.Atoi(matches[1])
+  year := date.InferYear(month, day, d.Reference)
   possibleDate := fmt.Sprintf("%04d-%s-%s", year, matches[3], matches[1])
   return d.getFormattedDateIfValid(possibleDate)
 }
 
+// findDateLong finds dd/mm/yyyy and dd/mm/yy dates
+func (d *Date) findDateLong(text string) string {
+	dateRegex := regexp.MustCompile(`(\d{1,2})([/-])(\d{1,2})([/-])(\d{4}|\d{2})\b`)
+	matches := dateRegex.FindStringSubmatch(text)
+  if len(matches) != 6 {
+    return ""
+  }
+  year := matches[5]
+  if len(year) == 2 {
+    year = "20" + year
+  }
+  possibleDate := fmt.Sprintf("%s-%s-%s", year, matches[3], matches[1])
+  return d.getFormattedDateIfValid(possibleDate)
+}
+
 func (d *Date) findDate(text string) string {
 	dateRegex := regexp.MustCompile(`(\d{4})([/-])(\d{1,2})([/-])(\d{1,2})`)
 	matches := dateRegex.FindStringSubmatch(text)
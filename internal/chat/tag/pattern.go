@@ -0,0 +1,41 @@
+package tag
+
+import (
+  "regexp"
+  "strconv"
+)
+
+// Pattern finds the first match of a regular expression, its value is
+// the first group of the match, "32" for `\b(\d+)\s*kg\b` and "32kg".
+type Pattern struct {
+  value   string
+  asint   int
+  pattern *regexp.Regexp
+}
+
+func NewPattern(expr string) *Pattern {
+  return &Pattern {
+    pattern: regexp.MustCompile(expr),
+  }
+}
+
+func (p *Pattern) Parse(text string) bool {
+  p.value = ""
+  p.asint = 0
+  matches := p.pattern.FindStringSubmatch(text)
+  if len(matches) < 2 {
+    return false
+  }
+
+  p.value = matches[1]
+  p.asint, _ = strconv.Atoi(p.value)
+  return true
+}
+
+func (p *Pattern) Value() string {
+  return p.value
+}
+
+func (p *Pattern) ValueAsInt() int {
+  return p.asint
+}
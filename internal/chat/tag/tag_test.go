@@ -3,6 +3,7 @@ package tag
 import (
   "fmt"
   "testing"
+  "time"
   "github.com/stretchr/testify/assert"
 )
 
@@ -84,6 +85,7 @@ func TestTagSet(t *testing.T) {
 
 func TestDate(t *testing.T) {
   date := NewDate()
+  date.Reference = time.Date(2025, time.December, 31, 12, 0, 0, 0, time.UTC)
 
   tests := []TestCase{
     {"2025/12/02",  true, "2025-12-02", 0},
@@ -95,6 +97,10 @@ func TestDate(t *testing.T) {
     {"25/12", true, "2025-12-25", 0},
     {"5/8",   true, "2025-08-05", 0},
 
+    //DD/MM/YYYY and DD/MM/YY
+    {"20/01/2024", true, "2024-01-20", 0},
+    {"20/1/24",    true, "2024-01-20", 0},
+
     // Invalids
     {"2025/13/02", false, "", 0},
     {"32/02",      false, "", 0},
@@ -109,3 +115,31 @@ func TestDate(t *testing.T) {
                  fmt.Sprintf("test: %d", index))
   }
 }
+
+func TestDateAcrossNewYear(t *testing.T) {
+  date := NewDate()
+  date.Reference = time.Date(2026, time.January, 2, 8, 0, 0, 0, time.UTC)
+  assert.True(t, date.Parse("30/12"))
+  assert.Equal(t, "2025-12-30", date.Value(), "30/12 sent in January is from last year")
+  assert.True(t, date.Parse("2/1"))
+  assert.Equal(t, "2026-01-02", date.Value())
+}
+
+func TestPattern(t *testing.T) {
+  tag := NewPattern(`\b(\d{1,3})\s*kg\b`)
+  tests := []TestCase{
+    {"1111 m angus 32kg", true, "32", 32},
+    {"1111 m angus 32 kg", true, "32", 32},
+    {"1111 m angus", false, "", 0},
+    {"1111 m angus 32kgs", false, "", 0},
+  }
+  for index, test := range tests {
+    found := tag.Parse(test.Input)
+    assert.Equal(t, test.Found, found,
+                 fmt.Sprintf("test: %d", index))
+    assert.Equal(t, test.Value, tag.Value(),
+                 fmt.Sprintf("test: %d", index))
+    assert.Equal(t, test.ValueInt, tag.ValueAsInt(),
+                 fmt.Sprintf("test: %d", index))
+  }
+}
@@ -4,9 +4,8 @@ import (
   "fmt"
   "log"
   "strings"
-  "context"
+  "time"
   "posso-help/internal/area"
-  "posso-help/internal/db"
   "posso-help/internal/date"
   "posso-help/internal/utils"
   "go.mongodb.org/mongo-driver/bson"
@@ -33,6 +32,10 @@ type TemperatureEntry struct {
 }
 
 type TemperatureMessage struct {
+  LineErrors
+  LineClaims
+  // Time the message was received, dates are relative to it
+  Received time.Time
   Entries []*TemperatureEntry
   Area *area.Area
 }
@@ -45,9 +48,14 @@ func (b *TemperatureMessage) GetCollection() string {
 func (t *TemperatureMessage) Parse(message string) bool {
   found := false
   lines := strings.Split(message, "\n")
-  for _, line := range lines {
+  for index, line := range lines {
+    if date.HasBadDate(line) {
+      t.AddLineError(index, line, BAD_DATE)
+      continue
+    }
     if entry := t.parseTemperatureLine(line); entry != nil {
       t.Entries = append(t.Entries, entry)
+      t.ClaimLine(index)
       found = true
     }
   }
@@ -55,23 +63,29 @@ func (t *TemperatureMessage) Parse(message string) bool {
 }
 
 func (t *TemperatureMessage) parseTemperatureLine(line string) (*TemperatureEntry) {
-  var day, month int
   var temperature int // in celcius 
   var celcius rune
   line = utils.SanitizeLine(line)
 
-  n, err := fmt.Sscanf(line, "%d/%d %d%c\n", &day, &month, &temperature, &celcius)
-  if err == nil && n == 4 && celcius == 'c' {
+  // The line starts with the date, dd/mm, dd/mm/yy or dd/mm/yyyy
+  first, rest, _ := strings.Cut(line, " ")
+  temperatureDate, found := date.ParseDate(first, t.Received)
+  if !found {
+    return nil
+  }
+
+  n, err := fmt.Sscanf(rest, "%d%c\n", &temperature, &celcius)
+  if err == nil && n == 2 && celcius == 'c' {
     return &TemperatureEntry{
-      Date: date.MonthDayToUTC(month, day),
+      Date: temperatureDate,
       Temperature: temperature,
     }
   }
 
-  n, err = fmt.Sscanf(line, "%d/%d %d %c\n", &day, &month, &temperature, &celcius)
-  if err == nil && n == 4 && celcius == 'c' {
+  n, err = fmt.Sscanf(rest, "%d %c\n", &temperature, &celcius)
+  if err == nil && n == 2 && celcius == 'c' {
     return &TemperatureEntry{
-      Date: date.MonthDayToUTC(month, day),
+      Date: temperatureDate,
       Temperature: temperature,
     }
   }
@@ -96,12 +110,11 @@ func (r *TemperatureMessage) Text(lang string) string {
 }
 
 func (b *TemperatureMessage) Insert(bmv *BaseMessageValues) error {
-  temps := db.GetCollection("temperature")
   for _, temp := range b.Entries {
     document := bmv.ToMap()
     document = append(document, bson.E{Key: "temperature", Value: temp.Temperature})
     document = append(document, bson.E{Key: "date", Value: temp.Date})
-    _, err := temps.InsertOne(context.TODO(), document)
+    err := insertRecord(bmv, "temperature", document)
     if err != nil {
       return err
     }
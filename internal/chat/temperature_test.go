@@ -2,11 +2,12 @@ package chat
 
 import (
   "testing"
+  "time"
   "github.com/stretchr/testify/assert"
 )
 
 func TestTemperatureMessage(t *testing.T) {
-  tm := &TemperatureMessage{}
+  tm := &TemperatureMessage{Received: time.Date(2025, time.December, 31, 12, 0, 0, 0, time.UTC)}
   input := "02/04 80c\n25/12 75C\nanystring\n30/01 40Cans"
   assert.Equal(t, tm.Parse(input), true, "Could not parse temperature message")
   assert.Equal(t, len(tm.Entries), 2, "Wrong number of temperature entries")
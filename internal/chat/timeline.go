@@ -0,0 +1,120 @@
+package chat
+
+import (
+  "fmt"
+  "sort"
+  "context"
+  "posso-help/internal/date"
+  "posso-help/internal/db"
+  "go.mongodb.org/mongo-driver/bson"
+)
+
+// Timeline type of the births of the calves of a cow
+const CALVING = "calving"
+
+// TimelineEvent is a record of an animal, with the WhatsApp message
+// that produced it when there is one.
+type TimelineEvent struct {
+  Date       string         `json:"date"`
+  // Collection of the record, or CALVING
+  Type       string         `json:"type"`
+  ReportedBy string         `json:"reported_by"`
+  Record     bson.M         `json:"record"`
+  Message    *ParsedMessage `json:"message,omitempty"`
+  collection string
+}
+
+type timelineQuery struct {
+  Collection string
+  Type       string
+  Filter     bson.M
+}
+
+// FindTimeline returns everything recorded about the animal of the
+// account: its birth, the births of its calves and the records of
+// EVENT_COLLECTIONS, oldest first.
+func FindTimeline(account string, tag int) ([]*TimelineEvent, error) {
+  queries := []timelineQuery{
+    {"births", "births", bson.M{"account": account, "tag": tag}},
+    {"births", CALVING, bson.M{"account": account, "dam": tag}},
+  }
+  for _, collection := range EVENT_COLLECTIONS {
+    queries = append(queries, timelineQuery{collection, collection, bson.M{"account": account, "tag": tag}})
+  }
+
+  events := []*TimelineEvent{}
+  ids := []interface{}{}
+  for _, query := range queries {
+    cursor, err := db.GetCollection(query.Collection).Find(context.TODO(), query.Filter)
+    if err != nil {
+      return nil, err
+    }
+    records := []bson.M{}
+    if err := cursor.All(context.TODO(), &records); err != nil {
+      return nil, err
+    }
+    for _, record := range records {
+      date, _ := record["date"].(string)
+      reporter, _ := record["created_by"].(string)
+      events = append(events, &TimelineEvent{
+        Date: date,
+        Type: query.Type,
+        ReportedBy: reporter,
+        Record: record,
+        collection: query.Collection,
+      })
+      ids = append(ids, record["_id"])
+    }
+  }
+
+  if len(ids) > 0 {
+    filter := bson.M{"account": account, "changes.id": bson.M{"$in": ids}}
+    cursor, err := db.GetCollection(MessagesCollection).Find(context.TODO(), filter)
+    if err != nil {
+      return nil, err
+    }
+    messages := []*ParsedMessage{}
+    if err := cursor.All(context.TODO(), &messages); err != nil {
+      return nil, err
+    }
+    attachMessages(events, messages)
+  }
+
+  sortTimeline(events)
+  return events, nil
+}
+
+// attachMessages links every event to the message that inserted its
+// record, or else to the first message that updated it.
+func attachMessages(events []*TimelineEvent, messages []*ParsedMessage) {
+  inserted := map[string]*ParsedMessage{}
+  updated := map[string]*ParsedMessage{}
+  for _, message := range messages {
+    for _, change := range message.Changes {
+      key := fmt.Sprintf("%s/%v", change.Collection, change.ID)
+      if change.Operation == INSERT {
+        inserted[key] = message
+      } else if updated[key] == nil {
+        updated[key] = message
+      }
+    }
+  }
+
+  for _, event := range events {
+    key := fmt.Sprintf("%s/%v", event.collection, event.Record["_id"])
+    event.Message = inserted[key]
+    if event.Message == nil {
+      event.Message = updated[key]
+    }
+    if event.ReportedBy == "" && event.Message != nil {
+      event.ReportedBy = event.Message.Name
+    }
+  }
+}
+
+// sortTimeline sorts the events oldest first
+func sortTimeline(events []*TimelineEvent) {
+  sort.SliceStable(events, func(i, j int) bool {
+    return date.Before(events[i].Date, events[j].Date)
+  })
+}
@@ -0,0 +1,45 @@
+package chat
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAttachMessages(t *testing.T) {
+  birthID := primitive.NewObjectID()
+  weightID := primitive.NewObjectID()
+  events := []*TimelineEvent{
+    {Type: "births", ReportedBy: "Ana", Record: bson.M{"_id": birthID}, collection: "births"},
+    {Type: "weights", Record: bson.M{"_id": weightID}, collection: "weights"},
+    {Type: "movements", Record: bson.M{"_id": primitive.NewObjectID()}, collection: "movements"},
+  }
+  birth := &ParsedMessage{Name: "Ana", RawMessage: "1234 f nelore", Changes: []*Change{
+    {Collection: "births", Operation: INSERT, ID: birthID},
+  }}
+  calfTag := &ParsedMessage{Name: "Bia", RawMessage: "brinco 1234 bez 99", Changes: []*Change{
+    {Collection: "births", Operation: UPDATE, ID: birthID},
+  }}
+  weight := &ParsedMessage{Name: "Bia", RawMessage: "1234 320kg", Changes: []*Change{
+    {Collection: "weights", Operation: INSERT, ID: weightID},
+  }}
+  attachMessages(events, []*ParsedMessage{calfTag, birth, weight})
+
+  assert.Equal(t, birth, events[0].Message, "The message that inserted the record wins")
+  assert.Equal(t, weight, events[1].Message)
+  assert.Equal(t, "Bia", events[1].ReportedBy, "The sender reported records without created_by")
+  assert.Nil(t, events[2].Message, "Records can be uploaded without a message")
+}
+
+func TestSortTimeline(t *testing.T) {
+  events := []*TimelineEvent{
+    {Date: "2025-03-01T00:00:00-03:00"},
+    {Date: "2025-01-15T00:00:00Z"},
+    {Date: "2025-03-01T01:00:00Z"},
+  }
+  sortTimeline(events)
+  assert.Equal(t, "2025-01-15T00:00:00Z", events[0].Date)
+  assert.Equal(t, "2025-03-01T01:00:00Z", events[1].Date, "Dates are compared as times")
+  assert.Equal(t, "2025-03-01T00:00:00-03:00", events[2].Date)
+}
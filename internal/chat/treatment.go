@@ -0,0 +1,201 @@
+package chat
+
+import (
+  "fmt"
+  "log"
+  "strconv"
+  "strings"
+  "time"
+  "posso-help/internal/date"
+  "posso-help/internal/product"
+  "posso-help/internal/utils"
+  "go.mongodb.org/mongo-driver/bson"
+)
+
+// Data formats for Treatment data
+// "vacina aftosa 1234 1235 1236"
+// "tratamento 5678 ivermectina"
+
+const VACCINE = "vaccine"
+const TREATMENT = "treatment"
+
+// Keywords that start a treatment line (English and Portuguese)
+var TREATMENT_KEYWORDS = map[string]string{
+  "vacina"     : VACCINE,
+  "vacinação"  : VACCINE,
+  "vacinacao"  : VACCINE,
+  "vaccine"    : VACCINE,
+  "tratamento" : TREATMENT,
+  "treatment"  : TREATMENT,
+  "medicação"  : TREATMENT,
+  "medicacao"  : TREATMENT,
+}
+
+type TreatmentEntry struct {
+  Type    string
+  Product *product.Product
+  Tags    []int
+}
+
+type TreatmentMessage struct {
+  LineErrors
+  LineClaims
+  // Time the message was received, dates are relative to it
+  Received time.Time
+  Date string
+  Entries []*TreatmentEntry
+  ProductParser *product.ProductParser
+  Total int
+}
+
+// SetDate sets the date of the message, shared by the records
+func (t *TreatmentMessage) SetDate(date string) {
+  t.Date = date
+}
+
+func (t *TreatmentMessage) GetCollection() string {
+  return "treatments"
+}
+
+func (t *TreatmentMessage) Parse(message string) bool {
+  found := false
+  lines := strings.Split(message, "\n")
+  for index, line := range lines {
+    if date, found := t.parseDateLine(index, line, t.Received); found {
+      t.Date = date
+    }
+    if entry := t.parseAsTreatmentLine(line); entry != nil {
+      t.Entries = append(t.Entries, entry)
+      t.Total += len(entry.Tags)
+      t.ClaimLine(index)
+      found = true
+      continue
+    }
+    t.diagnoseTreatmentLine(index, line)
+  }
+  return found
+}
+
+func (t *TreatmentMessage) parseAsTreatmentLine(line string) (*TreatmentEntry) {
+  if t.ProductParser == nil {
+    return nil
+  }
+
+  words := strings.Fields(utils.SanitizeLine(line))
+  if len(words) < 3 {
+    return nil
+  }
+
+  treatmentType, ok := TREATMENT_KEYWORDS[words[0]]
+  if !ok {
+    return nil
+  }
+
+  // Every number is a tag, everything else is the product name
+  entry := &TreatmentEntry{Type: treatmentType}
+  productWords := []string{}
+  for _, word := range words[1:] {
+    if tag, err := strconv.Atoi(word); err == nil {
+      if tag > 0 {
+        entry.Tags = append(entry.Tags, tag)
+      }
+      continue
+    }
+    productWords = append(productWords, word)
+  }
+
+  if len(entry.Tags) == 0 || len(productWords) == 0 {
+    return nil
+  }
+
+  // Try the full product name first ("febre aftosa"), then each word
+  if found, ok := t.ProductParser.MatchProduct(strings.Join(productWords, " ")); ok {
+    entry.Product = found
+    return entry
+  }
+  for _, word := range productWords {
+    if found, ok := t.ProductParser.MatchProduct(word); ok {
+      entry.Product = found
+      return entry
+    }
+  }
+
+  log.Printf("Treatment line with unknown product: %s", line)
+  return nil
+}
+
+// diagnoseTreatmentLine adds a line error when the line looks like a
+// treatment line, "{keyword} {product} {tags}", but its product is not
+// known.
+func (t *TreatmentMessage) diagnoseTreatmentLine(index int, line string) {
+  words := strings.Fields(utils.SanitizeLine(line))
+  if len(words) < 3 {
+    return
+  }
+  if _, ok := TREATMENT_KEYWORDS[words[0]]; !ok {
+    return
+  }
+  tags, productWords := 0, 0
+  for _, word := range words[1:] {
+    if tag, err := strconv.Atoi(word); err == nil {
+      if tag > 0 {
+        tags++
+      }
+      continue
+    }
+    productWords++
+  }
+  if tags > 0 && productWords > 0 {
+    t.AddLineError(index, line, UNKNOWN_PRODUCT)
+  }
+}
+
+func (t *TreatmentMessage) Text(lang string) string {
+  reply := map[string]string {
+    "en-US" : "Zap Manejo has detected treatment data. " +
+              "We added %d treatments.",
+    "pt-BR" : "Zap Manejo detectou dados de tratamento. " +
+              "Adicionamos %d tratamentos.",
+  }
+  withdrawal := map[string]string {
+    "en-US" : "\n%s: do not sell before %s",
+    "pt-BR" : "\n%s: não vender antes de %s",
+  }
+
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+
+  text := fmt.Sprintf(reply[lang], t.Total)
+  for _, entry := range t.Entries {
+    if entry.Product.WithdrawalDays <= 0 {
+      continue
+    }
+    until := date.Day(product.WithdrawalUntil(t.Date, entry.Product.WithdrawalDays), t.Received.Location())
+    text += fmt.Sprintf(withdrawal[lang], entry.Product.Name, until)
+  }
+  return text
+}
+
+func (t *TreatmentMessage) Insert(bmv *BaseMessageValues) error {
+  if t.Date == "" {
+    t.Date = bmv.Date
+  }
+  for _, entry := range t.Entries {
+    until := product.WithdrawalUntil(t.Date, entry.Product.WithdrawalDays)
+    for _, tag := range entry.Tags {
+      document := bmv.ToMapWithDate(t.Date)
+      document = append(document, bson.E{Key: "tag", Value: tag})
+      document = append(document, bson.E{Key: "type", Value: entry.Type})
+      document = append(document, bson.E{Key: "product", Value: entry.Product.Name})
+      document = append(document, bson.E{Key: "withdrawal_days", Value: entry.Product.WithdrawalDays})
+      document = append(document, bson.E{Key: "withdrawal_until", Value: until})
+      err := insertRecord(bmv, "treatments", document)
+      if err != nil {
+        return err
+      }
+    }
+  }
+  return nil
+}
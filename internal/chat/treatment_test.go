@@ -0,0 +1,54 @@
+package chat
+
+import (
+  "testing"
+  "posso-help/internal/product"
+  "github.com/stretchr/testify/assert"
+)
+
+// Helper to create a mock ProductParser for testing
+func createTestProductParser() *product.ProductParser {
+  pp := &product.ProductParser{}
+  pp.AddProduct("aftosa", "aftosa;febre aftosa", 0)
+  pp.AddProduct("ivermectina", "ivermectina;iver", 35)
+  return pp
+}
+
+func TestTreatmentMessage(t *testing.T) {
+  input := `10/03
+vacina aftosa 1234 1235 1236
+tratamento 5678 ivermectina`
+
+  tm := &TreatmentMessage{ProductParser: createTestProductParser()}
+  assert.True(t, tm.Parse(input), "Could not parse treatment message")
+  assert.Equal(t, 4, tm.Total, "Total treatments do not match")
+  assert.Equal(t, 2, len(tm.Entries), "Should have 2 entries")
+
+  assert.Equal(t, VACCINE, tm.Entries[0].Type, "First entry should be a vaccine")
+  assert.Equal(t, "aftosa", tm.Entries[0].Product.Name, "Wrong product")
+  assert.Equal(t, []int{1234, 1235, 1236}, tm.Entries[0].Tags, "Wrong tags")
+
+  assert.Equal(t, TREATMENT, tm.Entries[1].Type, "Second entry should be a treatment")
+  assert.Equal(t, "ivermectina", tm.Entries[1].Product.Name, "Wrong product")
+  assert.Equal(t, 35, tm.Entries[1].Product.WithdrawalDays, "Wrong withdrawal days")
+  assert.Equal(t, []int{5678}, tm.Entries[1].Tags, "Wrong tags")
+}
+
+func TestTreatmentMultiWordProduct(t *testing.T) {
+  tm := &TreatmentMessage{ProductParser: createTestProductParser()}
+  assert.True(t, tm.Parse("vacinação febre aftosa 1234"), "Could not parse treatment")
+  assert.Equal(t, "aftosa", tm.Entries[0].Product.Name, "Wrong product")
+}
+
+func TestInvalidTreatmentLines(t *testing.T) {
+  tm := &TreatmentMessage{ProductParser: createTestProductParser()}
+  assert.Nil(t, tm.parseAsTreatmentLine("vacina aftosa"), "Treatment needs a tag")
+  assert.Nil(t, tm.parseAsTreatmentLine("vacina desconhecida 1234"), "Unknown product")
+  assert.Nil(t, tm.parseAsTreatmentLine("1234 m angus"), "Birth line is not a treatment")
+}
+
+func TestDiagnoseTreatmentLine(t *testing.T) {
+  tm := &TreatmentMessage{ProductParser: createTestProductParser()}
+  assert.False(t, tm.Parse("vacina brucelose 1234 1235\nvacina aftosa\n1236 morreu"))
+  assert.Equal(t, []*LineError{{1, "vacina brucelose 1234 1235", UNKNOWN_PRODUCT}}, tm.GetLineErrors())
+}
@@ -0,0 +1,11 @@
+package twintag
+
+import (
+  "posso-help/internal/chat/tag"
+)
+
+func New() tag.Tag {
+  return tag.NewStringSet(
+    tag.NewString("twins", []string{"gemeos", "gêmeos", "gemeas", "gêmeas", "gemeo", "gêmeo", "gemea", "gêmea", "twins", "twin"}),
+  )
+}
@@ -0,0 +1,33 @@
+package twintag
+
+import (
+  "fmt"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+type TestCase struct {
+  Input string
+  Found bool 
+  Value string
+  ValueInt int
+}
+
+func TestTwinTag(t *testing.T) {
+  tests := []TestCase{
+    {"gemeos",             true,  "twins", 0},
+    {"1111 m angus gêmeos", true, "twins", 0},
+    {"twin",               true,  "twins", 0},
+    {"1111 m angus",       false, "",      0},
+  }
+  for index, test := range tests {
+    twins := New()
+    found := twins.Parse(test.Input)
+    assert.Equal(t, test.Found, found, 
+                 fmt.Sprintf("test: %d", index))
+    assert.Equal(t, test.Value, twins.Value(), 
+                 fmt.Sprintf("test: %d", index))
+    assert.Equal(t, test.ValueInt, twins.ValueAsInt(), 
+                 fmt.Sprintf("test: %d", index))
+  }
+}
@@ -0,0 +1,158 @@
+package chat
+
+import (
+  "fmt"
+  "log"
+  "strings"
+  "context"
+  "posso-help/internal/animal"
+  "posso-help/internal/db"
+  "posso-help/internal/utils"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/mongo"
+  "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Data formats for Undo commands
+// "desfazer"
+// "undo"
+
+// Keywords that undo the last message of the sender (English and Portuguese)
+var UNDO_KEYWORDS = []string{"desfazer", "undo"}
+
+type UndoMessage struct {
+  // Type of the undone message, empty when there was nothing to undo
+  MessageType string
+  Removed map[string]int
+  Restored map[string]int
+}
+
+func (u *UndoMessage) GetCollection() string {
+  return "undo"
+}
+
+func (u *UndoMessage) Parse(message string) bool {
+  return utils.StringIsOneOf(utils.SanitizeLine(message), UNDO_KEYWORDS)
+}
+
+// Insert reverts the changes of the most recent message sent from the
+// same phone that changed any records and was not undone yet.  When the
+// message held records of several parsers, all of them are reverted.
+func (u *UndoMessage) Insert(bmv *BaseMessageValues) error {
+  u.Removed = map[string]int{}
+  u.Restored = map[string]int{}
+
+  messages := db.GetCollection(MessagesCollection)
+  filter := bson.M{
+    "account": bmv.Account,
+    "phone": bmv.PhoneNumber,
+    "changes.0": bson.M{"$exists": true},
+    "reverted": bson.M{"$ne": true},
+  }
+  opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+  last := &ParsedMessage{}
+  err := messages.FindOne(context.TODO(), filter, opts).Decode(last)
+  if err == mongo.ErrNoDocuments {
+    log.Printf("undo: nothing to undo for %s\n", bmv.PhoneNumber)
+    return nil
+  }
+  if err != nil {
+    return err
+  }
+
+  // Every parser of the message saved its own document
+  parsed := []*ParsedMessage{last}
+  if last.MessageId != "" {
+    filter["message_id"] = last.MessageId
+    sort := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
+    cursor, err := messages.Find(context.TODO(), filter, sort)
+    if err != nil {
+      return err
+    }
+    parsed = []*ParsedMessage{}
+    if err := cursor.All(context.TODO(), &parsed); err != nil {
+      return err
+    }
+  }
+
+  types := []string{}
+  for _, message := range parsed {
+    if err := u.revert(message); err != nil {
+      return err
+    }
+    types = append([]string{message.MessageType}, types...)
+
+    update := bson.M{"$set": bson.M{
+      "reverted": true,
+      "reverted_by": bmv.Name,
+      "reverted_at": bmv.Date,
+    }}
+    _, err = messages.UpdateOne(context.TODO(), bson.M{"_id": message.ID}, update)
+    if err != nil {
+      return err
+    }
+  }
+  u.MessageType = strings.Join(types, ", ")
+
+  // The animals registry is derived from the reverted records
+  if u.Removed["births"] + u.Restored["births"] + u.Removed[DeathsCollection] + u.Removed["movements"] > 0 {
+    if _, err := animal.Rebuild(bmv.Account); err != nil {
+      log.Printf("Error rebuilding animals of %s: %v\n", bmv.Account, err)
+    }
+  }
+  return nil
+}
+
+// revert reverts the changes of the message in reverse order, so
+// records are restored to the state before the first change.
+func (u *UndoMessage) revert(message *ParsedMessage) error {
+  for index := len(message.Changes) - 1; index >= 0; index-- {
+    change := message.Changes[index]
+    if err := change.revert(); err != nil {
+      return err
+    }
+    if change.Operation == INSERT {
+      u.Removed[change.Collection]++
+    } else {
+      u.Restored[change.Collection]++
+    }
+  }
+  return nil
+}
+
+func (u *UndoMessage) Text(lang string) string {
+  nothing := map[string]string {
+    "en-US" : "Zap Manejo found no message to undo.",
+    "pt-BR" : "Zap Manejo não encontrou mensagem para desfazer.",
+  }
+  reply := map[string]string {
+    "en-US" : "Zap Manejo has undone your last %s message.",
+    "pt-BR" : "Zap Manejo desfez sua última mensagem de %s.",
+  }
+  removed := map[string]string {
+    "en-US" : "\nRemoved:",
+    "pt-BR" : "\nRemovidos:",
+  }
+  restored := map[string]string {
+    "en-US" : "\nRestored:",
+    "pt-BR" : "\nRestaurados:",
+  }
+
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+
+  if u.MessageType == "" {
+    return nothing[lang]
+  }
+
+  text := fmt.Sprintf(reply[lang], u.MessageType)
+  if len(u.Removed) > 0 {
+    text += removed[lang] + formatCounts(u.Removed)
+  }
+  if len(u.Restored) > 0 {
+    text += restored[lang] + formatCounts(u.Restored)
+  }
+  return text
+}
@@ -0,0 +1,25 @@
+package chat
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestUndoMessageParse(t *testing.T) {
+  assert.True(t, (&UndoMessage{}).Parse("desfazer"), "Could not parse desfazer")
+  assert.True(t, (&UndoMessage{}).Parse("  UNDO "), "Could not parse undo")
+  assert.False(t, (&UndoMessage{}).Parse("desfazer 1234"), "Undo takes no arguments")
+  assert.False(t, (&UndoMessage{}).Parse("1234 morreu"), "Death is not an undo")
+}
+
+func TestUndoMessageText(t *testing.T) {
+  um := &UndoMessage{}
+  assert.Equal(t, "Zap Manejo found no message to undo.", um.Text("en-US"))
+
+  um = &UndoMessage{
+    MessageType: "births",
+    Removed: map[string]int{"births": 2, "areas": 1},
+    Restored: map[string]int{},
+  }
+  assert.Equal(t, "Zap Manejo desfez sua última mensagem de births.\nRemovidos:\n  areas: 1\n  births: 2", um.Text("pt-BR"))
+}
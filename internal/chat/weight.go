@@ -0,0 +1,222 @@
+package chat
+
+import (
+  "fmt"
+  "log"
+  "time"
+  "regexp"
+  "unicode"
+  "strings"
+  "context"
+  "posso-help/internal/area"
+  "posso-help/internal/db"
+  "posso-help/internal/utils"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/mongo"
+  "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Data formats for Weight data
+// "1234 350kg"
+// "1234 350 kg"
+// "1234 350.5 kg"
+// "1234 350,5 kg"
+
+// A decimal comma, "350,5"
+var decimalCommaRegex = regexp.MustCompile(`(\d),(\d)`)
+
+type WeightEntry struct {
+  Id       int
+  Weight   float64 // in kg
+  Previous float64 // previous weighing in kg, zero if none
+  Gain     float64 // kg gained since the previous weighing
+  Days     int     // days since the previous weighing
+  ADG      float64 // average daily gain in kg/day
+}
+
+type WeightMessage struct {
+  LineErrors
+  LineClaims
+  // Time the message was received, dates are relative to it
+  Received time.Time
+  Date string
+  Entries []*WeightEntry
+  Area *area.Area
+  AreaParser *area.AreaParser
+  Total int
+}
+
+// SetDate sets the date of the message, shared by the records
+func (w *WeightMessage) SetDate(date string) {
+  w.Date = date
+}
+
+func (w *WeightMessage) GetCollection() string {
+  return "weights"
+}
+
+func (w *WeightMessage) Parse(message string) bool {
+  found := false
+  lines := strings.Split(message, "\n")
+  for index, line := range lines {
+    if date, found := w.parseDateLine(index, line, w.Received); found {
+      w.Date = date
+    }
+    if entry := w.parseAsWeightLine(line); entry != nil {
+      w.Entries = append(w.Entries, entry)
+      w.Total++
+      w.ClaimLine(index)
+      found = true
+      continue
+    }
+    w.diagnoseWeightLine(index, line)
+    if w.AreaParser != nil {
+      if areaName, found := w.AreaParser.ParseAsAreaLine(line); found {
+        w.Area = &area.Area{Name:areaName}
+      }
+    }
+  }
+
+  if found && w.Area == nil {
+    w.Area = &area.Area{Name: "unknown"}
+  }
+
+  return found
+}
+
+func (w *WeightMessage) parseAsWeightLine(line string) (*WeightEntry) {
+  var num int
+  var weight float64
+  var unit string
+  line = utils.SanitizeLine(line)
+  // Brazilian weights use a decimal comma, like birth weights
+  line = decimalCommaRegex.ReplaceAllString(line, "$1.$2")
+
+  // Support both 350kg and 350 kg (with space)
+  line = strings.Replace(line, "kg", " kg", 1)
+  n, err := fmt.Sscanf(line, "%d %f %s", &num, &weight, &unit)
+  if err == nil && n == 3 && num > 0 && weight > 0 && unit == "kg" {
+    return &WeightEntry{Id: num, Weight: weight}
+  }
+  return nil
+}
+
+// A weight followed by its unit, "350kg", "350,5 kg" or "350 kgs"
+var weightUnitRegex = regexp.MustCompile(`\d\s*(kg|kgs|quilos?|kilos?)\b`)
+
+// diagnoseWeightLine adds a line error when the line looks like a weight
+// line, "{tag} {weight} kg", but the weight could not be read.
+func (w *WeightMessage) diagnoseWeightLine(index int, line string) {
+  words, found := startsWithTag(line)
+  if !found || len(words) < 2 || !unicode.IsDigit(rune(words[1][0])) {
+    return
+  }
+  if weightUnitRegex.MatchString(strings.Join(words[1:], " ")) {
+    w.AddLineError(index, line, BAD_WEIGHT)
+  }
+}
+
+func (w *WeightMessage) Text(lang string) string {
+  reply := map[string]string {
+    "en-US" : "Zap Manejo has detected weight data. " +
+              "We added %d weights to area %s.",
+    "pt-BR" : "Zap Manejo detectou dados de pesagem. " +
+              "Adicionamos %d pesagens à área %s.",
+  }
+  firstWeighing := map[string]string {
+    "en-US" : "\n%d: %.1f kg (first weighing)",
+    "pt-BR" : "\n%d: %.1f kg (primeira pesagem)",
+  }
+  gain := map[string]string {
+    "en-US" : "\n%d: %.1f kg, %+.1f kg in %d days (%.2f kg/day)",
+    "pt-BR" : "\n%d: %.1f kg, %+.1f kg em %d dias (%.2f kg/dia)",
+  }
+
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+
+  text := fmt.Sprintf(reply[lang], w.Total, w.Area.Name)
+  for _, entry := range w.Entries {
+    if entry.Previous == 0 {
+      text += fmt.Sprintf(firstWeighing[lang], entry.Id, entry.Weight)
+      continue
+    }
+    text += fmt.Sprintf(gain[lang], entry.Id, entry.Weight,
+                        entry.Gain, entry.Days, entry.ADG)
+  }
+  return text
+}
+
+// findPreviousWeight returns the most recent weighing of the tag that
+// happened before the given date, or nil if the animal was never weighed.
+// Dates are stored in UTC, see date.UTC, so they compare as text.
+func (w *WeightMessage) findPreviousWeight(weights *mongo.Collection, account string, tag int, before string) (bson.M, error) {
+  filter := bson.M{"account": account, "tag": tag, "date": bson.M{"$lt": before}}
+  opts := options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}})
+  previous := bson.M{}
+  err := weights.FindOne(context.TODO(), filter, opts).Decode(&previous)
+  if err == mongo.ErrNoDocuments {
+    return nil, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+  return previous, nil
+}
+
+// computeGain fills in the gain and average daily gain of the entry
+// relative to the previous weighing.
+func (entry *WeightEntry) computeGain(previousWeight float64, previousDate, currentDate string) {
+  entry.Previous = previousWeight
+  entry.Gain = entry.Weight - previousWeight
+
+  from, err := time.Parse(time.RFC3339, previousDate)
+  if err != nil {
+    log.Printf("could not parse previous weight date %s: %v", previousDate, err)
+    return
+  }
+  to, err := time.Parse(time.RFC3339, currentDate)
+  if err != nil {
+    log.Printf("could not parse weight date %s: %v", currentDate, err)
+    return
+  }
+  entry.Days = int(to.Sub(from).Hours() / 24)
+  if entry.Days > 0 {
+    entry.ADG = entry.Gain / float64(entry.Days)
+  }
+}
+
+func (w *WeightMessage) Insert(bmv *BaseMessageValues) error {
+  weights := db.GetCollection("weights")
+  weighDate := bmv.Date
+  if w.Date != "" {
+    weighDate = w.Date
+  }
+  for _, entry := range w.Entries {
+    previous, err := w.findPreviousWeight(weights, bmv.Account, entry.Id, weighDate)
+    if err != nil {
+      log.Printf("error reading previous weight for %d: %v\n", entry.Id, err)
+    }
+    if previous != nil {
+      previousWeight, _ := previous["weight"].(float64)
+      previousDate, _ := previous["date"].(string)
+      entry.computeGain(previousWeight, previousDate, weighDate)
+    }
+
+    document := bmv.ToMapWithDate(w.Date)
+    document = append(document, bson.E{Key: "tag", Value: entry.Id})
+    document = append(document, bson.E{Key: "weight", Value: entry.Weight})
+    document = append(document, bson.E{Key: "area", Value: w.Area.Name})
+    if entry.Previous > 0 {
+      document = append(document, bson.E{Key: "gain", Value: entry.Gain})
+      document = append(document, bson.E{Key: "adg", Value: entry.ADG})
+    }
+    err = insertRecord(bmv, "weights", document)
+    if err != nil {
+      return err
+    }
+  }
+  return nil
+}
@@ -0,0 +1,53 @@
+package chat
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestWeightMessage(t *testing.T) {
+  wm := &WeightMessage{}
+  input := "15/03\n1234 350kg\n1235 412 kg\n1236 298.5 KG\nanystring"
+  assert.True(t, wm.Parse(input), "Could not parse weight message")
+  assert.Equal(t, 3, wm.Total, "Wrong number of weight entries")
+  assert.Equal(t, 1234, wm.Entries[0].Id, "Wrong tag")
+  assert.Equal(t, 350.0, wm.Entries[0].Weight, "Wrong weight value")
+  assert.Equal(t, 412.0, wm.Entries[1].Weight, "Wrong weight value")
+  assert.Equal(t, 298.5, wm.Entries[2].Weight, "Wrong weight value")
+  assert.NotEqual(t, "", wm.Date, "Date line was not parsed")
+  assert.Equal(t, "unknown", wm.Area.Name, "Area should be unknown")
+}
+
+func TestParseAsWeightLine(t *testing.T) {
+  wm := &WeightMessage{}
+  assert.Nil(t, wm.parseAsWeightLine("1234 m angus"), "Birth line is not a weight")
+  assert.Nil(t, wm.parseAsWeightLine("1234 morreu"), "Death line is not a weight")
+  assert.Nil(t, wm.parseAsWeightLine("15/02 25mm"), "Rain line is not a weight")
+  assert.Nil(t, wm.parseAsWeightLine("1234 350"), "Weight needs a kg unit")
+  assert.Nil(t, wm.parseAsWeightLine("0 350kg"), "Tag must be greater than zero")
+}
+
+func TestWeightGain(t *testing.T) {
+  entry := &WeightEntry{Id: 1234, Weight: 380}
+  entry.computeGain(350, "2025-01-01T00:00:00Z", "2025-01-31T00:00:00Z")
+  assert.Equal(t, 30.0, entry.Gain, "Wrong gain")
+  assert.Equal(t, 30, entry.Days, "Wrong number of days")
+  assert.Equal(t, 1.0, entry.ADG, "Wrong average daily gain")
+}
+
+func TestWeightDecimalComma(t *testing.T) {
+  wm := &WeightMessage{}
+  assert.True(t, wm.Parse("1234 350,5kg\n1235 280,25 kg"), "Could not parse weights with a decimal comma")
+  assert.Equal(t, 350.5, wm.Entries[0].Weight)
+  assert.Equal(t, 280.25, wm.Entries[1].Weight)
+  assert.Empty(t, wm.GetLineErrors())
+}
+
+func TestDiagnoseWeightLine(t *testing.T) {
+  wm := &WeightMessage{}
+  assert.False(t, wm.Parse("1234 350.5.1kg\n1235 350 kgs\n1236 m nelore 32kg\n1237 cobra"))
+  assert.Equal(t, []*LineError{
+    {1, "1234 350.5.1kg", BAD_WEIGHT},
+    {2, "1235 350 kgs", BAD_WEIGHT},
+  }, wm.GetLineErrors())
+}
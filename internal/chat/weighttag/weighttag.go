@@ -0,0 +1,10 @@
+package weighttag
+
+import (
+  "posso-help/internal/chat/tag"
+)
+
+// Weight in kg, "32kg", "32 kg" or "32,5 quilos"
+func New() tag.Tag {
+  return tag.NewPattern(`\b(\d{1,4}(?:[.,]\d{1,2})?)\s*(?:kg|kgs|quilos|kilos)\b`)
+}
@@ -0,0 +1,35 @@
+package weighttag
+
+import (
+  "fmt"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+type TestCase struct {
+  Input string
+  Found bool 
+  Value string
+  ValueInt int
+}
+
+func TestWeightTag(t *testing.T) {
+  weight := New()
+  tests := []TestCase{
+    {"32kg",               true,  "32",   32},
+    {"1111 m angus 32 kg", true,  "32",   32},
+    {"32,5 quilos",        true,  "32,5", 0},
+    {"32.5kg",             true,  "32.5", 0},
+    {"1111 m angus",       false, "",     0},
+    {"parto 2",            false, "",     0},
+  }
+  for index, test := range tests {
+    found := weight.Parse(test.Input)
+    assert.Equal(t, test.Found, found, 
+                 fmt.Sprintf("test: %d", index))
+    assert.Equal(t, test.Value, weight.Value(), 
+                 fmt.Sprintf("test: %d", index))
+    assert.Equal(t, test.ValueInt, weight.ValueAsInt(), 
+                 fmt.Sprintf("test: %d", index))
+  }
+}
@@ -0,0 +1,188 @@
+package conversation
+
+import (
+  "context"
+  "fmt"
+  "log"
+  "strconv"
+  "strings"
+  "time"
+  "posso-help/internal/db"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/bson/primitive"
+  "go.mongodb.org/mongo-driver/mongo"
+  "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const PendingCollection = "pending_conversations"
+
+// How long a question waits for its reply.  Stale pending conversations
+// are ignored, and removed by the TTL index on expires_at.
+const Expiry = 24 * time.Hour
+
+// Kinds of question
+const NEW_AREA = "new_area"
+const CALF_TAG = "calf_tag"
+
+// Replies to a question
+const NONE = 0
+const YES  = 1
+const NO   = 2
+
+var YES_REPLIES = []string{"1", "sim", "s", "yes", "y"}
+var NO_REPLIES  = []string{"2", "não", "nao", "n", "no"}
+
+// Option is one of the numbered choices of a question, the sender
+// replies with its number.
+type Option struct {
+  Value      string             `bson:"value" json:"value"`
+  Label      string             `bson:"label" json:"label"`
+}
+
+// Pending is a parsed message waiting for the sender to answer a
+// question before its records are saved.  There is at most one pending
+// conversation per phone.
+type Pending struct {
+  ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+  Account    string             `bson:"account" json:"account"`
+  Phone      string             `bson:"phone" json:"phone"`
+  Name       string             `bson:"name" json:"name"`
+  Kind       string             `bson:"kind" json:"kind"`
+  Value      string             `bson:"value" json:"value"`
+  // Choices of the question, empty for yes or no questions
+  Options    []*Option          `bson:"options,omitempty" json:"options,omitempty"`
+  RawMessage string             `bson:"raw_message" json:"raw_message"`
+  MessageId  string             `bson:"message_id" json:"message_id"`
+  // Date of the original message, the records are saved with it
+  Date       string             `bson:"date" json:"date"`
+  // New areas of the message not created, their records have no area
+  Declined   []string           `bson:"declined,omitempty" json:"declined,omitempty"`
+  CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+  ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// Save stores the pending conversation, replacing any previous one of
+// the phone.
+func Save(pending *Pending, now time.Time) error {
+  pending.CreatedAt = now
+  pending.ExpiresAt = now.Add(Expiry)
+  filter := bson.M{"account": pending.Account, "phone": pending.Phone}
+  opts := options.Replace().SetUpsert(true)
+  _, err := db.GetCollection(PendingCollection).ReplaceOne(context.TODO(), filter, pending, opts)
+  if err != nil {
+    log.Printf("Error saving pending conversation: %v", err)
+  }
+  return err
+}
+
+// Find returns the pending conversation of the phone, or nil when there
+// is none or it expired.
+func Find(account, phone string, now time.Time) (*Pending, error) {
+  filter := bson.M{
+    "account": account,
+    "phone": phone,
+    "expires_at": bson.M{"$gt": now},
+  }
+  pending := &Pending{}
+  err := db.GetCollection(PendingCollection).FindOne(context.TODO(), filter).Decode(pending)
+  if err == mongo.ErrNoDocuments {
+    return nil, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+  return pending, nil
+}
+
+// Delete removes the pending conversation once it was answered
+func Delete(pending *Pending) error {
+  _, err := db.GetCollection(PendingCollection).DeleteOne(context.TODO(), bson.M{"_id": pending.ID})
+  return err
+}
+
+// ParseReply returns YES or NO when the message answers a question,
+// and NONE otherwise.
+func ParseReply(message string) int {
+  reply := strings.ToLower(strings.TrimSpace(message))
+  for _, yes := range YES_REPLIES {
+    if reply == yes {
+      return YES
+    }
+  }
+  for _, no := range NO_REPLIES {
+    if reply == no {
+      return NO
+    }
+  }
+  return NONE
+}
+
+// ParseAnswer returns the answer of the message to the question: the
+// number of the chosen option for questions with options, YES or NO
+// otherwise, and NONE when the message is not an answer.
+func (p *Pending) ParseAnswer(message string) int {
+  if len(p.Options) == 0 {
+    return ParseReply(message)
+  }
+  choice, err := strconv.Atoi(strings.TrimSpace(message))
+  if err != nil || choice < 1 || choice > len(p.Options) {
+    return NONE
+  }
+  return choice
+}
+
+// Question returns the localized question to ask the sender
+func (p *Pending) Question(lang string) string {
+  questions := map[string]map[string]string {
+    NEW_AREA : {
+      "en-US" : "Create new area '%s'? reply 1=yes 2=no",
+      "pt-BR" : "Criar nova área '%s'? responda 1=sim 2=não",
+    },
+    CALF_TAG : {
+      "en-US" : "Which calf gets tag %s? reply with its number",
+      "pt-BR" : "Qual bezerro recebe o brinco %s? responda com o número",
+    },
+  }
+
+  question, ok := questions[p.Kind]
+  if !ok {
+    log.Printf("Unknown pending conversation kind: (%s)", p.Kind)
+    return ""
+  }
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+  text := fmt.Sprintf(question[lang], p.Value)
+  for index, option := range p.Options {
+    text += fmt.Sprintf("\n%d=%s", index+1, option.Label)
+  }
+  return text
+}
+
+// Discarded returns the localized reply when the sender answered no to
+// a new area, or the area could not be created.  The records of the
+// message are saved without the area.
+func (p *Pending) Discarded(lang string, failed bool) string {
+  replies := map[string]string {
+    "en-US" : "Zap Manejo did not create area '%s', " +
+              "its records were saved in area unknown.",
+    "pt-BR" : "Zap Manejo não criou a área '%s', " +
+              "seus registros foram salvos na área unknown.",
+  }
+  failures := map[string]string {
+    "en-US" : "Zap Manejo could not create area '%s', " +
+              "its records were saved in area unknown.",
+    "pt-BR" : "Zap Manejo não conseguiu criar a área '%s', " +
+              "seus registros foram salvos na área unknown.",
+  }
+
+  if lang != "pt-BR" && lang != "en-US" {
+    log.Printf("Unsupported or Unknown Language: (%s)", lang)
+    lang = "pt-BR"
+  }
+  if failed {
+    return fmt.Sprintf(failures[lang], p.Value)
+  }
+  return fmt.Sprintf(replies[lang], p.Value)
+}
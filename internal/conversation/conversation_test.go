@@ -0,0 +1,45 @@
+package conversation
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestParseReply(t *testing.T) {
+  assert.Equal(t, YES, ParseReply("1"))
+  assert.Equal(t, YES, ParseReply(" Sim "))
+  assert.Equal(t, NO, ParseReply("2"))
+  assert.Equal(t, NO, ParseReply("NÃO"))
+  assert.Equal(t, NONE, ParseReply("1234 m nelore"))
+  assert.Equal(t, NONE, ParseReply("3"))
+}
+
+func TestQuestion(t *testing.T) {
+  pending := &Pending{Kind: NEW_AREA, Value: "pasto nrte"}
+  assert.Equal(t, "Create new area 'pasto nrte'? reply 1=yes 2=no", pending.Question("en-US"))
+  assert.Equal(t, "Criar nova área 'pasto nrte'? responda 1=sim 2=não", pending.Question("pt-BR"))
+  assert.Equal(t, "", (&Pending{Kind: "unknown"}).Question("pt-BR"))
+}
+
+func TestQuestionOptions(t *testing.T) {
+  pending := &Pending{Kind: CALF_TAG, Value: "5678", Options: []*Option{
+    {"650000000000000000000001", "f nelore 2025-09-10"},
+    {"650000000000000000000002", "m nelore 2025-09-10"},
+  }}
+  assert.Equal(t, "Which calf gets tag 5678? reply with its number\n" +
+                  "1=f nelore 2025-09-10\n2=m nelore 2025-09-10", pending.Question("en-US"))
+
+  assert.Equal(t, 2, pending.ParseAnswer(" 2 "))
+  assert.Equal(t, NONE, pending.ParseAnswer("3"))
+  assert.Equal(t, NONE, pending.ParseAnswer("sim"))
+  assert.Equal(t, YES, (&Pending{Kind: NEW_AREA}).ParseAnswer("sim"))
+}
+
+func TestPendingDiscarded(t *testing.T) {
+  pending := &Pending{Kind: NEW_AREA, Value: "pasto nrte"}
+  assert.Equal(t, "Zap Manejo did not create area 'pasto nrte', its records were saved in area unknown.",
+               pending.Discarded("en-US", false))
+  assert.Equal(t, "Zap Manejo não conseguiu criar a área 'pasto nrte', seus registros foram salvos na área unknown.",
+               pending.Discarded("pt-BR", true))
+}
+
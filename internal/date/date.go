@@ -1,47 +1,196 @@
 package date
 
 import (
-  "fmt"
+  "regexp"
+  "strconv"
   "strings"
   "time"
 )
 
-// Dates will be detected as:
-// dd/mm 
-func ParseAsDateLine(line string) (string, bool) {
+// A dd/mm date without a year is taken as the latest date that is not
+// more than MaxFutureDays after the message was sent, so 30/12 sent on
+// the 2nd of January is from the previous year.
+const MaxFutureDays = 7
+
+// dd/mm, dd/mm/yy or dd/mm/yyyy at the start of a word
+var dateRegex = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{4}|\d{2}))?`)
 
-  var day, month int
+// Dates will be detected as:
+// dd/mm
+// dd/mm/yy
+// dd/mm/yyyy
+// The year of dd/mm dates is inferred from the reference, the time the
+// message was sent, or now when the reference is zero.
+func ParseAsDateLine(line string, reference time.Time) (string, bool) {
+  for _, part := range strings.Fields(line) {
+    if date, found := ParseDate(part, reference); found {
+      return date, true
+    }
+  }
+  return "", false
+}
 
-  parts := strings.Split(line, " ")
-  for _, part := range(parts)  {
-    n, err := fmt.Sscanf(part, "%d/%d", &day, &month)
+// ParseDate parses a word starting with a date, see ParseAsDateLine
+func ParseDate(word string, reference time.Time) (string, bool) {
+  year, month, day, found := splitDate(word)
+  if !found {
+    return "", false
+  }
+  if year == 0 {
+    year = InferYear(month, day, reference)
+  }
+  if !ValidDate(year, month, day) {
+    return "", false
+  }
+  return DateIn(year, month, day, location(reference)), true
+}
 
-    if err != nil {
+// HasBadDate reports whether the line has a dd/mm date that does not
+// exist, like 31/02 or 10/13.
+func HasBadDate(line string) bool {
+  for _, part := range strings.Fields(line) {
+    year, month, day, found := splitDate(part)
+    if !found {
       continue
     }
+    if year == 0 && !ValidMonthDay(month, day) {
+      return true
+    }
+    if year != 0 && !ValidDate(year, month, day) {
+      return true
+    }
+  }
+  return false
+}
 
-    if n != 2 {
-      continue
+// splitDate returns the year, month and day of the date at the start of
+// the word.  The year is zero when the date has none, and two digit
+// years are in the 2000s.
+func splitDate(word string) (int, int, int, bool) {
+  matches := dateRegex.FindStringSubmatch(word)
+  if matches == nil {
+    return 0, 0, 0, false
+  }
+  day, _ := strconv.Atoi(matches[1])
+  month, _ := strconv.Atoi(matches[2])
+  year := 0
+  if matches[3] != "" {
+    year, _ = strconv.Atoi(matches[3])
+    if len(matches[3]) == 2 {
+      year += 2000
     }
+  }
+  return year, month, day, true
+}
 
-    // @todo: Can do better than just this, but good enough for now.
-    if day > 31 {
-      continue
+// InferYear returns the year of the latest dd/mm date that is not more
+// than MaxFutureDays after the reference.
+func InferYear(month, day int, reference time.Time) int {
+  if reference.IsZero() {
+    reference = time.Now()
+  }
+  latest := reference.AddDate(0, 0, MaxFutureDays)
+  year := reference.Year()
+  for year > reference.Year() - 8 {
+    candidate := time.Date(year, time.Month(month), day, 0, 0, 0, 0, reference.Location())
+    if ValidDate(year, month, day) && !candidate.After(latest) {
+      return year
     }
+    year--
+  }
+  return reference.Year()
+}
 
-    // @todo: Can do better than just this, but good enough for now.
-    if month > 12 {
-      continue
+// ValidMonthDay reports whether the day exists in the month, 29/02 is
+// always accepted.
+func ValidMonthDay(month, day int) bool {
+  // 2024 is a leap year
+  return ValidDate(2024, month, day)
+}
+
+// ValidDate reports whether the date exists
+func ValidDate(year, month, day int) bool {
+  if month < 1 || month > 12 || day < 1 {
+    return false
+  }
+  tm := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+  return tm.Day() == day
+}
+
+// Dates are stored as RFC3339 in UTC, so they sort and compare as text
+// whatever the time zone of the account.  Dates of the messages are
+// midnight in the time zone of the account, and are rendered back in it.
+
+// MonthDayToUTC returns the dd/mm date as RFC3339, with the year
+// inferred from the reference.  The date is midnight in the time zone
+// of the reference.
+func MonthDayToUTC(month, day int, reference time.Time) string {
+  return DateIn(InferYear(month, day, reference), month, day, location(reference))
+}
+
+func DateToUTC(year, month, day int) string {
+  return DateIn(year, month, day, time.UTC)
+}
+
+// DateIn returns midnight of the date in the time zone, as RFC3339 in UTC
+func DateIn(year, month, day int, loc *time.Location) string {
+  tm := time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc) 
+  return UTC(tm)
+}
+
+// UTC returns the time as RFC3339 in UTC, the format dates are stored in
+func UTC(tm time.Time) string {
+  return tm.UTC().Format(time.RFC3339)
+}
+
+// Day returns the yyyy-mm-dd day of the RFC3339 date in the time zone.
+// Other values are cut to their first 10 characters.
+func Day(value string, loc *time.Location) string {
+  tm, err := time.Parse(time.RFC3339, value)
+  if err != nil {
+    if len(value) > 10 {
+      return value[:10]
     }
+    return value
+  }
+  return tm.In(loc).Format("2006-01-02")
+}
 
-    return MonthDayToUTC(month, day), true
+// Localize returns the RFC3339 date in the time zone, other values are
+// returned unchanged.
+func Localize(value string, loc *time.Location) string {
+  tm, err := time.Parse(time.RFC3339, value)
+  if err != nil {
+    return value
   }
+  return tm.In(loc).Format(time.RFC3339)
+}
 
-  return "", false
+// Normalize returns the RFC3339 date in UTC, the format dates are stored
+// in, other values are returned unchanged.
+func Normalize(value string) string {
+  tm, err := time.Parse(time.RFC3339, value)
+  if err != nil {
+    return value
+  }
+  return UTC(tm)
 }
 
-func MonthDayToUTC(month, day int) string {
-  currentYear := time.Now().Year()
-  tm := time.Date(currentYear, time.Month(month), day, 0, 0, 0, 0, time.UTC) 
-  return tm.Format(time.RFC3339)
+func location(reference time.Time) *time.Location {
+  if reference.IsZero() {
+    return time.UTC
+  }
+  return reference.Location()
+}
+
+// Before reports whether the first RFC3339 date is before the second.
+// Dates are compared as times, since they can be in different time zones,
+// or as text when they can not be parsed.
+func Before(first, second string) bool {
+  firstTime, errFirst := time.Parse(time.RFC3339, first)
+  secondTime, errSecond := time.Parse(time.RFC3339, second)
+  if errFirst != nil || errSecond != nil {
+    return first < second
+  }
+  return firstTime.Before(secondTime)
 }
@@ -2,27 +2,122 @@ package date
 
 import (
   "testing"
+  "time"
   "github.com/stretchr/testify/assert"
 )
 
+// Messages in the tests are sent on the 15th of September 2025
+var reference = time.Date(2025, time.September, 15, 10, 0, 0, 0, time.UTC)
+
 func TestParseAsDateLine(t *testing.T) {
-  date, found := ParseAsDateLine("20/01")
+  date, found := ParseAsDateLine("20/01", reference)
   assert.Equal(t, found, true)
   assert.Equal(t, date, "2025-01-20T00:00:00Z")
 
-  date, found = ParseAsDateLine("01/11")
+  date, found = ParseAsDateLine("01/09", reference)
   assert.Equal(t, found, true)
-  assert.Equal(t, date, "2025-11-01T00:00:00Z")
+  assert.Equal(t, date, "2025-09-01T00:00:00Z")
 
-  date, found = ParseAsDateLine("2/8")
+  date, found = ParseAsDateLine("2/8", reference)
   assert.Equal(t, found, true)
   assert.Equal(t, date, "2025-08-02T00:00:00Z")
 
-  date, found = ParseAsDateLine("  2/8  ")
+  date, found = ParseAsDateLine("  2/8  ", reference)
   assert.Equal(t, found, true)
   assert.Equal(t, date, "2025-08-02T00:00:00Z")
 
-  date, found = ParseAsDateLine("text before  2/8  text after")
+  date, found = ParseAsDateLine("text before  2/8  text after", reference)
   assert.Equal(t, found, true)
   assert.Equal(t, date, "2025-08-02T00:00:00Z")
 }
+
+func TestParseAsDateLineYear(t *testing.T) {
+  date, found := ParseAsDateLine("01/11", reference)
+  assert.Equal(t, found, true)
+  assert.Equal(t, date, "2024-11-01T00:00:00Z", "Dates far in the future are from last year")
+
+  date, found = ParseAsDateLine("20/09", reference)
+  assert.Equal(t, found, true)
+  assert.Equal(t, date, "2025-09-20T00:00:00Z", "Dates a few days ahead are kept")
+
+  newYear := time.Date(2026, time.January, 2, 8, 0, 0, 0, time.UTC)
+  date, found = ParseAsDateLine("30/12", newYear)
+  assert.Equal(t, found, true)
+  assert.Equal(t, date, "2025-12-30T00:00:00Z", "Dates across the new year")
+
+  date, found = ParseAsDateLine("29/02", reference)
+  assert.Equal(t, found, true)
+  assert.Equal(t, date, "2024-02-29T00:00:00Z", "29/02 is from the last leap year")
+
+  date, found = ParseAsDateLine("20/01/2024", reference)
+  assert.Equal(t, found, true)
+  assert.Equal(t, date, "2024-01-20T00:00:00Z")
+
+  date, found = ParseAsDateLine("20/01/24", reference)
+  assert.Equal(t, found, true)
+  assert.Equal(t, date, "2024-01-20T00:00:00Z")
+}
+
+func TestParseAsDateLineBadDate(t *testing.T) {
+  _, found := ParseAsDateLine("31/02", reference)
+  assert.Equal(t, found, false)
+
+  _, found = ParseAsDateLine("10/13", reference)
+  assert.Equal(t, found, false)
+
+  _, found = ParseAsDateLine("0/10", reference)
+  assert.Equal(t, found, false)
+
+  _, found = ParseAsDateLine("29/02/2025", reference)
+  assert.Equal(t, found, false)
+}
+
+func TestHasBadDate(t *testing.T) {
+  assert.True(t, HasBadDate("31/02"))
+  assert.True(t, HasBadDate("nascimentos 32/01"))
+  assert.True(t, HasBadDate("29/02/2025"))
+  assert.False(t, HasBadDate("29/02"))
+  assert.False(t, HasBadDate("29/02/2024"))
+  assert.False(t, HasBadDate("15/02 25mm"))
+  assert.False(t, HasBadDate("1234 m nelore"))
+}
+
+func TestMonthDayToUTC(t *testing.T) {
+  assert.Equal(t, "2025-04-02T00:00:00Z", MonthDayToUTC(4, 2, reference))
+  assert.Equal(t, "2024-12-25T00:00:00Z", MonthDayToUTC(12, 25, reference))
+}
+
+func TestParseAsDateLineTimezone(t *testing.T) {
+  saoPaulo := time.FixedZone("BRT", -3 * 60 * 60)
+  // 22:30 on the 14th in Brazil is already the 15th in UTC
+  evening := time.Date(2025, time.September, 14, 22, 30, 0, 0, saoPaulo)
+  date, found := ParseAsDateLine("14/09", evening)
+  assert.Equal(t, found, true)
+  assert.Equal(t, date, "2025-09-14T03:00:00Z", "Midnight in Brazil, stored in UTC")
+}
+
+func TestDay(t *testing.T) {
+  saoPaulo := time.FixedZone("BRT", -3 * 60 * 60)
+  tokyo := time.FixedZone("JST", 9 * 60 * 60)
+  assert.Equal(t, "2025-09-14", Day("2025-09-14T03:00:00Z", saoPaulo))
+  assert.Equal(t, "2025-09-14", Day(DateIn(2025, 9, 14, tokyo), tokyo), "Midnight in Tokyo is the day before in UTC")
+  assert.Equal(t, "2025-09-14", Day("2025-09-14 legacy", tokyo))
+  assert.Equal(t, "-", Day("-", tokyo))
+}
+
+func TestLocalize(t *testing.T) {
+  saoPaulo := time.FixedZone("BRT", -3 * 60 * 60)
+  assert.Equal(t, "2025-09-14T22:30:00-03:00", Localize("2025-09-15T01:30:00Z", saoPaulo))
+  assert.Equal(t, "pasto norte", Localize("pasto norte", saoPaulo), "Other values are unchanged")
+}
+
+func TestBefore(t *testing.T) {
+  assert.True(t, Before("2025-03-01T02:00:00Z", "2025-03-01T01:00:00-03:00"), "Dates are compared as times")
+  assert.False(t, Before("2025-03-01T01:00:00-03:00", "2025-03-01T02:00:00Z"))
+  assert.True(t, Before("2025-03-01", "2025-03-02"), "Other values are compared as text")
+}
+
+func TestNormalize(t *testing.T) {
+  assert.Equal(t, "2025-09-15T01:30:00Z", Normalize("2025-09-14T22:30:00-03:00"))
+  assert.Equal(t, "pasto norte", Normalize("pasto norte"), "Other values are unchanged")
+}
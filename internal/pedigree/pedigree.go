@@ -0,0 +1,236 @@
+package pedigree
+
+import (
+  "context"
+  "errors"
+  "log"
+  "sort"
+  "strconv"
+  "posso-help/internal/db"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/mongo"
+)
+
+// Generations walked when none are asked for, and the most allowed
+const DefaultGenerations = 3
+const MaxGenerations = 10
+
+var ErrNotFound = errors.New("animal not found")
+
+// Animal is a births record, the sire is a name, or the tag of a bull
+// of the herd.
+type Animal struct {
+  Tag   int    `bson:"tag" json:"tag"`
+  Sex   string `bson:"sex" json:"sex,omitempty"`
+  Breed string `bson:"breed" json:"breed,omitempty"`
+  Date  string `bson:"date" json:"date,omitempty"`
+  Dam   int    `bson:"dam" json:"dam,omitempty"`
+  Sire  string `bson:"sire" json:"sire,omitempty"`
+}
+
+// Lookup reads the animals of one account
+type Lookup interface {
+  // FindAnimal returns the animal with the tag, nil when there is none
+  FindAnimal(tag int) (*Animal, error)
+  // FindOffspring returns the animals whose dam or sire is the tag
+  FindOffspring(tag int) ([]*Animal, error)
+}
+
+// Node is an animal of the family tree.  Sires that are not animals of
+// the herd only have a name.
+type Node struct {
+  Tag      int     `json:"tag,omitempty"`
+  Name     string  `json:"name,omitempty"`
+  Sex      string  `json:"sex,omitempty"`
+  Breed    string  `json:"breed,omitempty"`
+  Date     string  `json:"date,omitempty"`
+  Dam      *Node   `json:"dam,omitempty"`
+  Sire     *Node   `json:"sire,omitempty"`
+  Children []*Node `json:"children,omitempty"`
+}
+
+type Pedigree struct {
+  Animal      *Node `json:"animal"`
+  Generations int   `json:"generations"`
+  // Offspring of the animal, with their own offspring
+  Descendants []*Node `json:"descendants"`
+  // The same ancestor appears on the dam and the sire sides
+  Inbred          bool     `json:"inbred"`
+  CommonAncestors []string `json:"common_ancestors,omitempty"`
+}
+
+// Build returns the ancestors and descendants of the animal, up to the
+// given number of generations, DefaultGenerations when zero.
+func Build(lookup Lookup, tag int, generations int) (*Pedigree, error) {
+  if generations <= 0 {
+    generations = DefaultGenerations
+  }
+  if generations > MaxGenerations {
+    generations = MaxGenerations
+  }
+
+  animal, err := lookup.FindAnimal(tag)
+  if err != nil {
+    return nil, err
+  }
+  if animal == nil {
+    return nil, ErrNotFound
+  }
+
+  p := &Pedigree{Generations: generations, Descendants: []*Node{}}
+  path := map[int]bool{animal.Tag: true}
+  p.Animal = newNode(animal)
+  if err := addAncestors(lookup, p.Animal, animal, generations, path); err != nil {
+    return nil, err
+  }
+  p.Descendants, err = findDescendants(lookup, animal.Tag, generations, path)
+  if err != nil {
+    return nil, err
+  }
+
+  // Inbreeding: an ancestor related through both parents
+  damSide := map[string]bool{}
+  collectAncestors(p.Animal.Dam, damSide)
+  sireSide := map[string]bool{}
+  collectAncestors(p.Animal.Sire, sireSide)
+  for id := range damSide {
+    if sireSide[id] {
+      p.CommonAncestors = append(p.CommonAncestors, id)
+    }
+  }
+  sort.Strings(p.CommonAncestors)
+  p.Inbred = len(p.CommonAncestors) > 0
+  return p, nil
+}
+
+func newNode(animal *Animal) *Node {
+  return &Node{Tag: animal.Tag, Sex: animal.Sex, Breed: animal.Breed, Date: animal.Date}
+}
+
+// addAncestors adds the dam and sire of the animal to its node.  The
+// path holds the tags being walked so bad data can not loop forever.
+func addAncestors(lookup Lookup, node *Node, animal *Animal, generations int, path map[int]bool) error {
+  if generations == 0 {
+    return nil
+  }
+
+  if animal.Dam > 0 {
+    dam, err := parentNode(lookup, animal.Dam, "", generations, path)
+    if err != nil {
+      return err
+    }
+    node.Dam = dam
+  }
+
+  if animal.Sire != "" {
+    // Sires of the herd are linked by tag, others only have a name
+    sireTag, err := strconv.Atoi(animal.Sire)
+    if err != nil || sireTag <= 0 {
+      node.Sire = &Node{Name: animal.Sire}
+      return nil
+    }
+    sire, err := parentNode(lookup, sireTag, animal.Sire, generations, path)
+    if err != nil {
+      return err
+    }
+    node.Sire = sire
+  }
+  return nil
+}
+
+func parentNode(lookup Lookup, tag int, name string, generations int, path map[int]bool) (*Node, error) {
+  if path[tag] {
+    log.Printf("pedigree: loop at tag %d\n", tag)
+    return &Node{Tag: tag, Name: name}, nil
+  }
+  parent, err := lookup.FindAnimal(tag)
+  if err != nil {
+    return nil, err
+  }
+  if parent == nil {
+    return &Node{Tag: tag, Name: name}, nil
+  }
+
+  node := newNode(parent)
+  path[tag] = true
+  defer delete(path, tag)
+  err = addAncestors(lookup, node, parent, generations-1, path)
+  return node, err
+}
+
+func findDescendants(lookup Lookup, tag int, generations int, path map[int]bool) ([]*Node, error) {
+  nodes := []*Node{}
+  if generations == 0 {
+    return nodes, nil
+  }
+  offspring, err := lookup.FindOffspring(tag)
+  if err != nil {
+    return nil, err
+  }
+  for _, animal := range offspring {
+    node := newNode(animal)
+    nodes = append(nodes, node)
+    // Untagged calves can not have offspring of their own
+    if generations == 1 || animal.Tag <= 0 || path[animal.Tag] {
+      continue
+    }
+    path[animal.Tag] = true
+    node.Children, err = findDescendants(lookup, animal.Tag, generations-1, path)
+    delete(path, animal.Tag)
+    if err != nil {
+      return nil, err
+    }
+  }
+  return nodes, nil
+}
+
+// collectAncestors adds the node and its ancestors to the set, by tag
+// or by name for sires outside the herd.
+func collectAncestors(node *Node, ancestors map[string]bool) {
+  if node == nil {
+    return
+  }
+  if node.Tag > 0 {
+    ancestors[strconv.Itoa(node.Tag)] = true
+  } else if node.Name != "" {
+    ancestors[node.Name] = true
+  }
+  collectAncestors(node.Dam, ancestors)
+  collectAncestors(node.Sire, ancestors)
+}
+
+// births is the Lookup of an account in the births collection
+type births struct {
+  account string
+}
+
+func NewLookup(account string) Lookup {
+  return &births{account: account}
+}
+
+func (b *births) FindAnimal(tag int) (*Animal, error) {
+  filter := bson.M{"account": b.account, "tag": tag}
+  animal := &Animal{}
+  err := db.GetCollection("births").FindOne(context.TODO(), filter).Decode(animal)
+  if err == mongo.ErrNoDocuments {
+    return nil, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+  return animal, nil
+}
+
+func (b *births) FindOffspring(tag int) ([]*Animal, error) {
+  filter := bson.M{
+    "account": b.account,
+    "$or": []bson.M{{"dam": tag}, {"sire": strconv.Itoa(tag)}},
+  }
+  cursor, err := db.GetCollection("births").Find(context.TODO(), filter)
+  if err != nil {
+    return nil, err
+  }
+  offspring := []*Animal{}
+  err = cursor.All(context.TODO(), &offspring)
+  return offspring, err
+}
@@ -0,0 +1,102 @@
+package pedigree
+
+import (
+  "strconv"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+// herd is a Lookup over animals in memory
+type herd []*Animal
+
+func (h herd) FindAnimal(tag int) (*Animal, error) {
+  for _, animal := range h {
+    if animal.Tag == tag {
+      return animal, nil
+    }
+  }
+  return nil, nil
+}
+
+func (h herd) FindOffspring(tag int) ([]*Animal, error) {
+  offspring := []*Animal{}
+  for _, animal := range h {
+    if animal.Dam == tag || animal.Sire == strconv.Itoa(tag) {
+      offspring = append(offspring, animal)
+    }
+  }
+  return offspring, nil
+}
+
+func TestBuildAncestors(t *testing.T) {
+  h := herd{
+    {Tag: 100, Sex: "f"},
+    {Tag: 200, Sex: "f", Dam: 100, Sire: "touro1"},
+    {Tag: 300, Sex: "m", Dam: 200, Sire: "touro2"},
+    {Tag: 400, Sex: "f", Dam: 200},
+  }
+  p, err := Build(h, 300, 0)
+  assert.Nil(t, err)
+  assert.Equal(t, DefaultGenerations, p.Generations)
+  assert.Equal(t, 300, p.Animal.Tag)
+  assert.Equal(t, 200, p.Animal.Dam.Tag)
+  assert.Equal(t, "touro2", p.Animal.Sire.Name)
+  assert.Equal(t, 100, p.Animal.Dam.Dam.Tag)
+  assert.Equal(t, "touro1", p.Animal.Dam.Sire.Name)
+  assert.False(t, p.Inbred)
+
+  p, err = Build(h, 300, 1)
+  assert.Nil(t, err)
+  assert.Nil(t, p.Animal.Dam.Dam, "Only one generation is walked")
+}
+
+func TestBuildDescendants(t *testing.T) {
+  h := herd{
+    {Tag: 100, Sex: "f"},
+    {Tag: 200, Sex: "f", Dam: 100},
+    {Tag: 0, Sex: "m", Dam: 200},
+    {Tag: 500, Sex: "m", Dam: 200},
+    {Tag: 600, Sex: "f", Sire: "500"},
+  }
+  p, err := Build(h, 100, 3)
+  assert.Nil(t, err)
+  assert.Equal(t, 1, len(p.Descendants))
+  assert.Equal(t, 200, p.Descendants[0].Tag)
+  assert.Equal(t, 2, len(p.Descendants[0].Children))
+  assert.Equal(t, 600, p.Descendants[0].Children[1].Children[0].Tag)
+
+  p, err = Build(h, 100, 1)
+  assert.Nil(t, err)
+  assert.Nil(t, p.Descendants[0].Children, "Only one generation is walked")
+}
+
+func TestBuildInbreeding(t *testing.T) {
+  h := herd{
+    {Tag: 100, Sex: "f"},
+    {Tag: 200, Sex: "f", Dam: 100},
+    {Tag: 300, Sex: "m", Dam: 100},
+    {Tag: 400, Sex: "f", Dam: 200, Sire: "300"},
+  }
+  p, err := Build(h, 400, 3)
+  assert.Nil(t, err)
+  assert.Equal(t, 300, p.Animal.Sire.Tag)
+  assert.True(t, p.Inbred)
+  assert.Equal(t, []string{"100"}, p.CommonAncestors)
+}
+
+func TestBuildNotFound(t *testing.T) {
+  _, err := Build(herd{}, 100, 3)
+  assert.Equal(t, ErrNotFound, err)
+}
+
+func TestBuildLoop(t *testing.T) {
+  h := herd{
+    {Tag: 100, Sex: "f", Dam: 200},
+    {Tag: 200, Sex: "f", Dam: 100},
+  }
+  p, err := Build(h, 100, MaxGenerations)
+  assert.Nil(t, err)
+  assert.Equal(t, 200, p.Animal.Dam.Tag)
+  assert.Equal(t, 100, p.Animal.Dam.Dam.Tag)
+  assert.Nil(t, p.Animal.Dam.Dam.Dam, "Loops are not walked")
+}
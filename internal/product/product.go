@@ -0,0 +1,76 @@
+package product
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"posso-help/internal/db"
+	"posso-help/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Product is a vaccine or medication that can be given to an animal.
+// WithdrawalDays is the number of days after application during which
+// the animal can not be sold or slaughtered.
+type Product struct {
+	Name           string `bson:"name"`
+	Matches        string `bson:"matches"`
+	WithdrawalDays int    `bson:"withdrawal_days"`
+}
+
+type ProductParser struct {
+	products []*Product
+}
+
+// LoadProductsByAccount loads products for the given account plus global products
+func (pp *ProductParser) LoadProductsByAccount(account string) error {
+	collection := db.GetCollection("products")
+
+	// Include both account-specific products and global products (all zeros account)
+	accounts := []string{account, "000000000000000000000000"}
+	filter := bson.M{"account": bson.M{"$in": accounts}}
+
+	cursor, err := collection.Find(context.TODO(), filter)
+	if err != nil {
+		log.Printf("Error reading products for account: %v", account)
+		return err
+	}
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		product := &Product{}
+		if err := cursor.Decode(product); err != nil {
+			log.Printf("Error decoding product document: %v", err)
+			continue
+		}
+		log.Printf("LoadProductsByAccount(%s): %s  %s", account, product.Name, product.Matches)
+		pp.products = append(pp.products, product)
+	}
+
+	return cursor.Err()
+}
+
+// MatchProduct checks if the given text matches any product's matches
+// Returns the product if found, nil otherwise
+func (pp *ProductParser) MatchProduct(text string) (*Product, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	for _, product := range pp.products {
+		matches := utils.SplitAndTrim(strings.ToLower(product.Matches))
+		if utils.StringIsOneOf(text, matches) {
+			log.Printf("MatchProduct: found, name=%s for text=%s", product.Name, text)
+			return product, true
+		}
+	}
+	return nil, false
+}
+
+// AddProduct adds a product to the parser (useful for testing)
+func (pp *ProductParser) AddProduct(name, matches string, withdrawalDays int) {
+	pp.products = append(pp.products, &Product{
+		Name:           name,
+		Matches:        matches,
+		WithdrawalDays: withdrawalDays,
+	})
+}
@@ -0,0 +1,56 @@
+package product
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"posso-help/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Withdrawal is a treated animal that is still inside the withdrawal
+// period of the product it received.
+type Withdrawal struct {
+	Tag             int    `bson:"tag" json:"tag"`
+	Product         string `bson:"product" json:"product"`
+	Date            string `bson:"date" json:"date"`
+	WithdrawalUntil string `bson:"withdrawal_until" json:"withdrawal_until"`
+}
+
+// WithdrawalUntil returns the date, in RFC3339 UTC, on which the
+// withdrawal period of a product applied on the given date ends.  Dates
+// are stored in UTC so FindActiveWithdrawals can compare them as text.
+func WithdrawalUntil(applied string, withdrawalDays int) string {
+	date, err := time.Parse(time.RFC3339, applied)
+	if err != nil {
+		log.Printf("WithdrawalUntil: invalid date %s: %v", applied, err)
+		return applied
+	}
+	return date.AddDate(0, 0, withdrawalDays).UTC().Format(time.RFC3339)
+}
+
+// FindActiveWithdrawals returns the treatments of the account whose
+// withdrawal period has not ended yet, which means the tags can not be sold.
+func FindActiveWithdrawals(account string, now time.Time) ([]*Withdrawal, error) {
+	collection := db.GetCollection("treatments")
+	filter := bson.M{
+		"account":          account,
+		"withdrawal_until": bson.M{"$gt": now.UTC().Format(time.RFC3339)},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "withdrawal_until", Value: -1}})
+	cursor, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		log.Printf("Error reading withdrawals for account: %v", account)
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	withdrawals := []*Withdrawal{}
+	if err := cursor.All(context.TODO(), &withdrawals); err != nil {
+		return nil, err
+	}
+	return withdrawals, nil
+}
@@ -0,0 +1,23 @@
+package product
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithdrawalUntil(t *testing.T) {
+	assert.Equal(t, "2025-04-14T00:00:00Z", WithdrawalUntil("2025-03-10T00:00:00Z", 35))
+	assert.Equal(t, "2025-03-10T00:00:00Z", WithdrawalUntil("2025-03-10T00:00:00Z", 0))
+	assert.Equal(t, "2025-04-14T03:00:00Z", WithdrawalUntil("2025-03-10T00:00:00-03:00", 35), "Stored in UTC")
+}
+
+func TestMatchProduct(t *testing.T) {
+	pp := &ProductParser{}
+	pp.AddProduct("ivermectina", "ivermectina;ivomec", 35)
+	found, ok := pp.MatchProduct(" Ivomec ")
+	assert.True(t, ok)
+	assert.Equal(t, "ivermectina", found.Name)
+	_, ok = pp.MatchProduct("unknown")
+	assert.False(t, ok)
+}
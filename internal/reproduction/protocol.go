@@ -0,0 +1,147 @@
+package reproduction
+
+import (
+	"context"
+	"log"
+	"strings"
+	"unicode"
+
+	"posso-help/internal/db"
+	"posso-help/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const ProtocolsCollection = "reproduction.protocols"
+
+// Treatment is a single medication, device or procedure of a protocol step
+type Treatment struct {
+	Medication string `bson:"medication,omitempty" json:"medication,omitempty"`
+	Device     string `bson:"device,omitempty" json:"device,omitempty"`
+	Procedure  string `bson:"procedure,omitempty" json:"procedure,omitempty"`
+	Notes      string `bson:"notes,omitempty" json:"notes,omitempty"`
+}
+
+// Step is one entry of the timeline_days of a protocol
+type Step struct {
+	StartDay   int          `bson:"start_day" json:"start_day"`
+	EndDay     int          `bson:"end_day" json:"end_day"`
+	Event      string       `bson:"event" json:"event"`
+	Treatments []*Treatment `bson:"treatments" json:"treatments"`
+}
+
+// Protocol is a reproduction protocol as defined in
+// db/schema/reproduction.protocols.json
+type Protocol struct {
+	Name          string   `bson:"name" json:"name"`
+	Matches       string   `bson:"matches" json:"matches"`
+	Description   string   `bson:"description" json:"description"`
+	TargetAnimals []string `bson:"target_animals" json:"target_animals"`
+	TimelineDays  []*Step  `bson:"timeline_days" json:"timeline_days"`
+}
+
+type ProtocolParser struct {
+	protocols []*Protocol
+}
+
+// LoadProtocolsByAccount loads protocols for the given account plus global protocols
+func (pp *ProtocolParser) LoadProtocolsByAccount(account string) error {
+	collection := db.GetCollection(ProtocolsCollection)
+
+	// Include both account-specific protocols and global protocols (all zeros account)
+	accounts := []string{account, "000000000000000000000000"}
+	filter := bson.M{"account": bson.M{"$in": accounts}}
+
+	cursor, err := collection.Find(context.TODO(), filter)
+	if err != nil {
+		log.Printf("Error reading protocols for account: %v", account)
+		return err
+	}
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		protocol := &Protocol{}
+		if err := cursor.Decode(protocol); err != nil {
+			log.Printf("Error decoding protocol document: %v", err)
+			continue
+		}
+		log.Printf("LoadProtocolsByAccount(%s): %s", account, protocol.Name)
+		pp.protocols = append(pp.protocols, protocol)
+	}
+
+	return cursor.Err()
+}
+
+// MatchProtocol finds the protocol referenced by the given text. The text
+// matches when it is one of the protocol's matches, or when it is one or
+// more whole words of the protocol name ignoring case, accents, spaces and
+// punctuation, so "cosynch" matches "7-day CO-Synch + CIDR". When several
+// names match, the one the text covers the most wins, so "ovsynch" picks
+// "Ovsynch" over "Ovsynch + CIDR".
+func (pp *ProtocolParser) MatchProtocol(text string) (*Protocol, bool) {
+	key := normalize(text)
+	if key == "" {
+		return nil, false
+	}
+	for _, protocol := range pp.protocols {
+		for _, match := range utils.SplitAndTrim(protocol.Matches) {
+			if normalize(match) == key {
+				log.Printf("MatchProtocol: found, name=%s for text=%s", protocol.Name, text)
+				return protocol, true
+			}
+		}
+	}
+
+	var best *Protocol
+	bestScore := 0.0
+	for _, protocol := range pp.protocols {
+		if !matchesNameWords(protocol.Name, key) {
+			continue
+		}
+		score := float64(len(key)) / float64(len(normalize(protocol.Name)))
+		if score > bestScore {
+			best = protocol
+			bestScore = score
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	log.Printf("MatchProtocol: found, name=%s for text=%s", best.Name, text)
+	return best, true
+}
+
+// matchesNameWords reports whether the key is a run of consecutive whole
+// words of the name, normalized and joined.
+func matchesNameWords(name, key string) bool {
+	words := strings.Fields(utils.NormalizeText(name))
+	for start := range words {
+		joined := ""
+		for _, word := range words[start:] {
+			joined += word
+			if joined == key {
+				return true
+			}
+			if len(joined) >= len(key) {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// AddProtocol adds a protocol to the parser (useful for testing)
+func (pp *ProtocolParser) AddProtocol(protocol *Protocol) {
+	pp.protocols = append(pp.protocols, protocol)
+}
+
+// normalize keeps only the lowercase letters and digits of the text
+func normalize(text string) string {
+	text = strings.ToLower(utils.RemoveAccents(text))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, text)
+}
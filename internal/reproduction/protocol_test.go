@@ -0,0 +1,90 @@
+package reproduction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestProtocol() *Protocol {
+	return &Protocol{
+		Name: "7-day CO-Synch + CIDR",
+		TimelineDays: []*Step{
+			{StartDay: 0, EndDay: 0, Event: "CIDR insertion", Treatments: []*Treatment{
+				{Medication: "GnRH"}, {Device: "CIDR insert"},
+			}},
+			{StartDay: 7, EndDay: 7, Event: "CIDR removal", Treatments: []*Treatment{
+				{Device: "CIDR removal"}, {Medication: "PGF2a"},
+			}},
+			{StartDay: 9, EndDay: 10, Event: "TAI", Treatments: []*Treatment{
+				{Procedure: "Artificial Insemination (AI)"}, {},
+			}},
+		},
+	}
+}
+
+func TestMatchProtocol(t *testing.T) {
+	pp := &ProtocolParser{}
+	pp.AddProtocol(createTestProtocol())
+	pp.AddProtocol(&Protocol{Name: "Ressincronização Super Precoce", Matches: "resinc;super precoce"})
+
+	protocol, found := pp.MatchProtocol("cosynch")
+	assert.True(t, found)
+	assert.Equal(t, "7-day CO-Synch + CIDR", protocol.Name)
+
+	protocol, found = pp.MatchProtocol("Co-Synch")
+	assert.True(t, found)
+	assert.Equal(t, "7-day CO-Synch + CIDR", protocol.Name)
+
+	protocol, found = pp.MatchProtocol("ressincronizacao")
+	assert.True(t, found)
+	assert.Equal(t, "Ressincronização Super Precoce", protocol.Name)
+
+	protocol, found = pp.MatchProtocol("resinc")
+	assert.True(t, found)
+	assert.Equal(t, "Ressincronização Super Precoce", protocol.Name)
+
+	_, found = pp.MatchProtocol("ovsynch")
+	assert.False(t, found)
+
+	_, found = pp.MatchProtocol("syn")
+	assert.False(t, found, "Only whole words of the name match")
+}
+
+func TestMatchProtocolSharedWord(t *testing.T) {
+	pp := &ProtocolParser{}
+	pp.AddProtocol(&Protocol{Name: "Ovsynch + CIDR"})
+	pp.AddProtocol(&Protocol{Name: "Ovsynch"})
+	pp.AddProtocol(&Protocol{Name: "CIDR 5 dias"})
+
+	protocol, found := pp.MatchProtocol("ovsynch")
+	assert.True(t, found)
+	assert.Equal(t, "Ovsynch", protocol.Name, "The name covered the most wins")
+
+	protocol, found = pp.MatchProtocol("Ovsynch CIDR")
+	assert.True(t, found)
+	assert.Equal(t, "Ovsynch + CIDR", protocol.Name)
+
+	protocol, found = pp.MatchProtocol("cidr")
+	assert.True(t, found)
+	assert.Equal(t, "CIDR 5 dias", protocol.Name)
+}
+
+func TestSchedule(t *testing.T) {
+	start := time.Date(2025, time.December, 28, 0, 0, 0, 0, time.UTC)
+	steps := createTestProtocol().Schedule(start)
+	assert.Equal(t, 3, len(steps))
+
+	assert.Equal(t, 0, steps[0].Day)
+	assert.Equal(t, "2025-12-28T00:00:00Z", steps[0].Date)
+	assert.Equal(t, []string{"GnRH", "CIDR insert"}, steps[0].Treatments)
+
+	assert.Equal(t, 7, steps[1].Day)
+	assert.Equal(t, "2026-01-04T00:00:00Z", steps[1].Date)
+
+	assert.Equal(t, 9, steps[2].Day)
+	assert.Equal(t, "2026-01-06T00:00:00Z", steps[2].Date)
+	assert.Equal(t, "2026-01-07T00:00:00Z", steps[2].EndDate)
+	assert.Equal(t, []string{"Artificial Insemination (AI)"}, steps[2].Treatments)
+}
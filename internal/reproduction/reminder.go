@@ -0,0 +1,51 @@
+package reproduction
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"posso-help/internal/scheduler"
+)
+
+// ScheduleReminders creates a scheduled task for every upcoming step of
+// the protocol, so the team is reminded of what to do with the animals
+// on the day of the step.  It returns the ids of the created tasks.
+func ScheduleReminders(account string, protocol *Protocol, tags []int, start time.Time, createdBy string) ([]interface{}, error) {
+	animals := []string{}
+	for _, tag := range tags {
+		animals = append(animals, strconv.Itoa(tag))
+	}
+
+	ids := []interface{}{}
+	for _, step := range protocol.Schedule(start) {
+		// Day 0 is the day the protocol was started, nothing to remind
+		if step.Day == 0 {
+			continue
+		}
+		dueAt, err := time.Parse(time.RFC3339, step.Date)
+		if err != nil {
+			log.Printf("ScheduleReminders: invalid step date %s: %v", step.Date, err)
+			continue
+		}
+		message := fmt.Sprintf("Zap Manejo: %s D%d - %s", protocol.Name, step.Day, step.Event)
+		if len(step.Treatments) > 0 {
+			message += "\n" + strings.Join(step.Treatments, "\n")
+		}
+		message += "\n" + strings.Join(animals, ", ")
+
+		task := &scheduler.Task{
+			Account:   account,
+			Message:   message,
+			DueAt:     dueAt,
+			CreatedBy: createdBy,
+		}
+		if err := scheduler.AddTask(task); err != nil {
+			return ids, err
+		}
+		ids = append(ids, task.ID)
+	}
+	return ids, nil
+}
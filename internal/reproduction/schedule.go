@@ -0,0 +1,73 @@
+package reproduction
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"posso-help/internal/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const InstancesCollection = "protocol_instances"
+
+// ScheduledStep is a protocol step with the actual dates for one animal
+type ScheduledStep struct {
+	Day        int      `bson:"day" json:"day"`
+	Date       string   `bson:"date" json:"date"`
+	EndDate    string   `bson:"end_date" json:"end_date"`
+	Event      string   `bson:"event" json:"event"`
+	Treatments []string `bson:"treatments" json:"treatments"`
+}
+
+// Description returns a short text of what has to be done in the treatment
+func (t *Treatment) Description() string {
+	parts := []string{}
+	for _, part := range []string{t.Medication, t.Device, t.Procedure} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Schedule computes the dated steps of the protocol when started on the
+// given day, day 0 of the protocol being the start date.
+func (p *Protocol) Schedule(start time.Time) []*ScheduledStep {
+	steps := []*ScheduledStep{}
+	for _, step := range p.TimelineDays {
+		scheduled := &ScheduledStep{
+			Day:        step.StartDay,
+			Date:       start.AddDate(0, 0, step.StartDay).UTC().Format(time.RFC3339),
+			EndDate:    start.AddDate(0, 0, step.EndDay).UTC().Format(time.RFC3339),
+			Event:      step.Event,
+			Treatments: []string{},
+		}
+		for _, treatment := range step.Treatments {
+			if description := treatment.Description(); description != "" {
+				scheduled.Treatments = append(scheduled.Treatments, description)
+			}
+		}
+		steps = append(steps, scheduled)
+	}
+	return steps
+}
+
+// StartProtocol stores a protocol instance for the animal, with the dated
+// steps computed from the start date, and returns the id of the instance.
+func StartProtocol(account string, tag int, protocol *Protocol, start time.Time) (interface{}, error) {
+	steps := protocol.Schedule(start)
+	document := bson.M{
+		"account":    account,
+		"tag":        tag,
+		"protocol":   protocol.Name,
+		"start_date": start.UTC().Format(time.RFC3339),
+		"steps":      steps,
+	}
+	result, err := db.GetCollection(InstancesCollection).InsertOne(context.TODO(), document)
+	if err != nil {
+		return nil, err
+	}
+	return result.InsertedID, nil
+}
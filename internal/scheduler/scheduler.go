@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+  "context"
+  "errors"
+  "log"
+  "time"
+  "posso-help/internal/account"
+  "posso-help/internal/db"
+  "posso-help/internal/textmsg"
+  "posso-help/internal/utils"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/mongo"
+  "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// A task that could not be sent to every phone is retried
+// MaxAttempts times, waiting attempts * RetryDelay between tries.
+const MaxAttempts = 5
+const RetryDelay = 5 * time.Minute
+
+// A task being sent is claimed for ClaimLease.  A task still being sent
+// after that was interrupted, and is claimed again by the next run.
+const ClaimLease = 10 * time.Minute
+
+// Start runs the scheduler in the background, checking for due tasks
+// every interval.
+func Start(interval time.Duration) {
+  log.Printf("Starting scheduler, interval: %v", interval)
+  go func() {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+      RunDueTasks(time.Now())
+      <-ticker.C
+    }
+  }()
+}
+
+// RunDueTasks sends every pending task that is due at the given time
+func RunDueTasks(now time.Time) {
+  for {
+    task, err := claimDueTask(now)
+    if err == mongo.ErrNoDocuments {
+      return
+    }
+    if err != nil {
+      log.Printf("Error claiming due task: %v", err)
+      return
+    }
+    runTask(task, now)
+  }
+}
+
+// claimDueTask marks the next due task as being sent, so a task is only
+// sent once even when several servers are running.
+func claimDueTask(now time.Time) (*Task, error) {
+  update := bson.M{"$set": bson.M{"status": SENDING, "claimed_at": now}}
+  opts := options.FindOneAndUpdate().
+    SetSort(bson.D{{Key: "due_at", Value: 1}}).
+    SetReturnDocument(options.After)
+  task := &Task{}
+  err := db.GetCollection(TasksCollection).
+    FindOneAndUpdate(context.TODO(), claimFilter(now), update, opts).Decode(task)
+  return task, err
+}
+
+// claimFilter matches the pending tasks that are due, and the tasks whose
+// claim expired because the server sending them stopped.
+func claimFilter(now time.Time) bson.M {
+  return bson.M{"$or": []bson.M{
+    {"status": PENDING, "due_at": bson.M{"$lte": now}},
+    {"status": SENDING, "claimed_at": bson.M{"$lte": now.Add(-ClaimLease)}},
+  }}
+}
+
+func runTask(task *Task, now time.Time) {
+  log.Printf("Running task %s for account %s", task.ID.Hex(), task.Account)
+  teams, err := account.FindTeamsByAccount(task.Account)
+  if err == nil && len(teams) == 0 {
+    err = errors.New("no team phones for account")
+  }
+
+  if err == nil {
+    phones := []string{}
+    for _, team := range teams {
+      phones = append(phones, team.PhoneNumber)
+    }
+    var deliveries []*Delivery
+    deliveries, err = sendTask(task, phones, sendMessage, now)
+    for _, delivery := range deliveries {
+      recordDelivery(delivery)
+    }
+  }
+
+  filter := bson.M{"_id": task.ID}
+  update := bson.M{"$set": taskUpdate(task, err, now)}
+  _, err = db.GetCollection(TasksCollection).UpdateOne(context.TODO(), filter, update)
+  if err != nil {
+    log.Printf("Error updating task %s: %v", task.ID.Hex(), err)
+  }
+}
+
+func sendMessage(phone, message string) error {
+  return textmsg.NewMessageSender(phone, message).Send()
+}
+
+// sendTask sends the task message to the phones it was not sent to yet,
+// adding them to SentTo.  It returns the deliveries to record and the
+// last send error.
+func sendTask(task *Task, phones []string, send func(string, string) error, now time.Time) ([]*Delivery, error) {
+  var err error
+  deliveries := []*Delivery{}
+  for _, phone := range phones {
+    if phone == "" || utils.StringIsOneOf(phone, task.SentTo) {
+      continue
+    }
+    delivery := &Delivery{
+      TaskID:  task.ID,
+      Account: task.Account,
+      Phone:   phone,
+      Message: task.Message,
+      Status:  SENT,
+      Date:    now,
+    }
+    if sendErr := send(phone, task.Message); sendErr != nil {
+      delivery.Status = FAILED
+      delivery.Error = sendErr.Error()
+      err = sendErr
+    } else {
+      task.SentTo = append(task.SentTo, phone)
+    }
+    deliveries = append(deliveries, delivery)
+  }
+  return deliveries, err
+}
+
+// taskUpdate counts the attempt and returns the fields to set on the
+// task: sent, retried later or failed after MaxAttempts.
+func taskUpdate(task *Task, err error, now time.Time) bson.M {
+  task.Attempts++
+  set := bson.M{"attempts": task.Attempts, "sent_to": task.SentTo}
+  switch {
+  case err == nil:
+    set["status"] = SENT
+    set["sent_at"] = now
+  case task.Attempts >= MaxAttempts:
+    log.Printf("Task %s failed after %d attempts: %v", task.ID.Hex(), task.Attempts, err)
+    set["status"] = FAILED
+    set["last_error"] = err.Error()
+  default:
+    log.Printf("Task %s will be retried: %v", task.ID.Hex(), err)
+    set["status"] = PENDING
+    set["last_error"] = err.Error()
+    set["due_at"] = retryAt(now, task.Attempts)
+  }
+  return set
+}
+
+// retryAt returns when a task that failed the given number of
+// attempts should be tried again.
+func retryAt(now time.Time, attempts int) time.Time {
+  return now.Add(time.Duration(attempts) * RetryDelay)
+}
+
+// recordDelivery keeps a record of every message sent, or tried to be
+// sent, for a task.
+func recordDelivery(delivery *Delivery) {
+  _, err := db.GetCollection(DeliveriesCollection).InsertOne(context.TODO(), delivery)
+  if err != nil {
+    log.Printf("Error recording delivery of task %s: %v", delivery.TaskID.Hex(), err)
+  }
+}
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+  "encoding/json"
+  "errors"
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+  "go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRetryAt(t *testing.T) {
+  now := time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC)
+  assert.Equal(t, now.Add(5*time.Minute), retryAt(now, 1), "first retry")
+  assert.Equal(t, now.Add(20*time.Minute), retryAt(now, 4), "fourth retry")
+}
+
+func TestClaimFilter(t *testing.T) {
+  now := time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC)
+  assert.Equal(t, bson.M{"$or": []bson.M{
+    {"status": PENDING, "due_at": bson.M{"$lte": now}},
+    {"status": SENDING, "claimed_at": bson.M{"$lte": now.Add(-10 * time.Minute)}},
+  }}, claimFilter(now), "Tasks being sent are only claimed again after the lease")
+}
+
+func TestSendTask(t *testing.T) {
+  now := time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC)
+  task := &Task{Account: "a1", Message: "D7 CIDR", SentTo: []string{"551"}}
+  sent := []string{}
+  send := func(phone, message string) error {
+    if phone == "553" {
+      return errors.New("unreachable")
+    }
+    sent = append(sent, phone)
+    return nil
+  }
+
+  deliveries, err := sendTask(task, []string{"551", "552", "", "553"}, send, now)
+  assert.EqualError(t, err, "unreachable")
+  assert.Equal(t, []string{"552"}, sent, "Phones already sent to are skipped")
+  assert.Equal(t, []string{"551", "552"}, task.SentTo)
+  assert.Equal(t, 2, len(deliveries), "Every try is recorded")
+  assert.Equal(t, &Delivery{Account: "a1", Phone: "552", Message: "D7 CIDR", Status: SENT, Date: now}, deliveries[0])
+  assert.Equal(t, FAILED, deliveries[1].Status)
+  assert.Equal(t, "unreachable", deliveries[1].Error)
+}
+
+func TestTaskUpdate(t *testing.T) {
+  now := time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC)
+  task := &Task{SentTo: []string{"551"}}
+  set := taskUpdate(task, nil, now)
+  assert.Equal(t, bson.M{"attempts": 1, "sent_to": []string{"551"}, "status": SENT, "sent_at": now}, set)
+
+  task = &Task{Attempts: 1}
+  set = taskUpdate(task, errors.New("unreachable"), now)
+  assert.Equal(t, PENDING, set["status"], "Failed tasks are retried")
+  assert.Equal(t, now.Add(10*time.Minute), set["due_at"])
+  assert.Equal(t, "unreachable", set["last_error"])
+
+  task = &Task{Attempts: MaxAttempts - 1}
+  set = taskUpdate(task, errors.New("unreachable"), now)
+  assert.Equal(t, FAILED, set["status"], "Tasks fail after MaxAttempts")
+  assert.Nil(t, set["due_at"])
+}
+
+func TestTaskRequestNewTask(t *testing.T) {
+  due := time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC)
+  request := &TaskRequest{}
+  err := json.Unmarshal([]byte(`{"message": "vacinar", "due_at": "2025-03-01T08:00:00Z",
+    "_id": "65f000000000000000000000", "status": "sent", "claimed_at": "2025-03-01T08:00:00Z"}`), request)
+  assert.Nil(t, err)
+
+  task := request.NewTask("acme", "Ana")
+  assert.Equal(t, &Task{Account: "acme", Message: "vacinar", DueAt: due, CreatedBy: "Ana"}, task,
+               "The state of the task is not taken from the client")
+}
+
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+  "context"
+  "log"
+  "time"
+  "posso-help/internal/db"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/bson/primitive"
+  "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const TasksCollection = "tasks"
+const DeliveriesCollection = "task_deliveries"
+
+// Task status
+const PENDING = "pending"
+const SENDING = "sending"
+const SENT    = "sent"
+const FAILED  = "failed"
+
+// Task is a reminder that is sent to the team phones of the account
+// once it is due.
+type Task struct {
+  ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+  Account   string             `bson:"account" json:"account"`
+  Message   string             `bson:"message" json:"message"`
+  DueAt     time.Time          `bson:"due_at" json:"due_at"`
+  Status    string             `bson:"status" json:"status"`
+  Attempts  int                `bson:"attempts" json:"attempts"`
+  LastError string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+  SentTo    []string           `bson:"sent_to" json:"sent_to"`
+  SentAt    *time.Time         `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
+  // When the task was last claimed to be sent, see ClaimLease
+  ClaimedAt *time.Time         `bson:"claimed_at,omitempty" json:"claimed_at,omitempty"`
+  CreatedBy string             `bson:"created_by" json:"created_by"`
+  CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Delivery is the record of a task message sent to one phone
+type Delivery struct {
+  TaskID  primitive.ObjectID `bson:"task_id" json:"task_id"`
+  Account string             `bson:"account" json:"account"`
+  Phone   string             `bson:"phone" json:"phone"`
+  Message string             `bson:"message" json:"message"`
+  Status  string             `bson:"status" json:"status"`
+  Error   string             `bson:"error,omitempty" json:"error,omitempty"`
+  Date    time.Time          `bson:"date" json:"date"`
+}
+
+// TaskRequest is a task as sent by a client, only the message and when
+// it is due.  The state of the task is kept by the server.
+type TaskRequest struct {
+  Message string    `json:"message"`
+  DueAt   time.Time `json:"due_at"`
+}
+
+// NewTask returns the task of the request for the account
+func (r *TaskRequest) NewTask(account, createdBy string) *Task {
+  return &Task{Account: account, Message: r.Message, DueAt: r.DueAt, CreatedBy: createdBy}
+}
+
+// AddTask stores a new pending task
+func AddTask(task *Task) error {
+  task.Status = PENDING
+  task.Attempts = 0
+  task.SentTo = []string{}
+  task.CreatedAt = time.Now()
+  result, err := db.GetCollection(TasksCollection).InsertOne(context.TODO(), task)
+  if err != nil {
+    log.Printf("Error inserting task: %v", err)
+    return err
+  }
+  task.ID = result.InsertedID.(primitive.ObjectID)
+  log.Printf("Task %s added for account %s due at %v", task.ID.Hex(), task.Account, task.DueAt)
+  return nil
+}
+
+// FindTasksByAccount returns the tasks of the account, next due first
+func FindTasksByAccount(account string) ([]*Task, error) {
+  filter := bson.M{"account": account}
+  opts := options.Find().SetSort(bson.D{{Key: "due_at", Value: 1}})
+  cursor, err := db.GetCollection(TasksCollection).Find(context.TODO(), filter, opts)
+  if err != nil {
+    log.Printf("Error reading tasks for account: %v", account)
+    return nil, err
+  }
+  defer cursor.Close(context.TODO())
+
+  tasks := []*Task{}
+  if err := cursor.All(context.TODO(), &tasks); err != nil {
+    return nil, err
+  }
+  return tasks, nil
+}
@@ -3,6 +3,7 @@ package utils
 import (
   "log"
   "strings"
+  "unicode"
 )
 
 func StringIsOneOf(in string, oneOf []string) bool {
@@ -48,3 +49,80 @@ func SplitAndTrim(str string) []string {
   log.Printf("SplitAndTrim(%s): [%+v] len: %d", str, parts, len(parts))
   return parts
 }
+
+var accentReplacer = strings.NewReplacer(
+  "á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+  "é", "e", "è", "e", "ê", "e", "ë", "e",
+  "í", "i", "ì", "i", "î", "i", "ï", "i",
+  "ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+  "ú", "u", "ù", "u", "û", "u", "ü", "u",
+  "ç", "c", "ñ", "n",
+  "Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+  "É", "E", "È", "E", "Ê", "E", "Ë", "E",
+  "Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+  "Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+  "Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+  "Ç", "C", "Ñ", "N",
+)
+
+// RemoveAccents replaces the accented letters used in Portuguese
+// with their plain ascii letter, "mediterrâneo" => "mediterraneo"
+func RemoveAccents(str string) string {
+  return accentReplacer.Replace(str)
+}
+
+// NormalizeText returns the text in lower case without accents, with
+// punctuation replaced by spaces and a single space between words,
+// "  Espírito-Santo " => "espirito santo"
+func NormalizeText(str string) string {
+  str = RemoveAccents(strings.ToLower(str))
+  str = strings.Map(func(r rune) rune {
+    if unicode.IsLetter(r) || unicode.IsDigit(r) {
+      return r
+    }
+    return ' '
+  }, str)
+  return strings.Join(strings.Fields(str), " ")
+}
+
+// EditDistance returns the optimal string alignment distance between
+// the strings: the number of letters inserted, deleted, replaced or
+// swapped with the next letter to turn a into b.
+func EditDistance(a, b string) int {
+  ra := []rune(a)
+  rb := []rune(b)
+  d := make([][]int, len(ra)+1)
+  for i := range d {
+    d[i] = make([]int, len(rb)+1)
+    d[i][0] = i
+  }
+  for j := range d[0] {
+    d[0][j] = j
+  }
+
+  for i := 1; i <= len(ra); i++ {
+    for j := 1; j <= len(rb); j++ {
+      cost := 1
+      if ra[i-1] == rb[j-1] {
+        cost = 0
+      }
+      d[i][j] = min(d[i-1][j] + 1, d[i][j-1] + 1, d[i-1][j-1] + cost)
+      if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+        d[i][j] = min(d[i][j], d[i-2][j-2] + 1)
+      }
+    }
+  }
+  return d[len(ra)][len(rb)]
+}
+
+// Similarity returns how close the strings are, from 0 for nothing in
+// common to 1 for equal strings, ignoring case and accents.
+func Similarity(a, b string) float64 {
+  a = RemoveAccents(strings.ToLower(a))
+  b = RemoveAccents(strings.ToLower(b))
+  length := max(len([]rune(a)), len([]rune(b)))
+  if length == 0 {
+    return 1
+  }
+  return 1 - float64(EditDistance(a, b)) / float64(length)
+}
@@ -25,3 +25,31 @@ func TestSplitAndTrim(t *testing.T) {
   assert.Equal(t, "one",  parts[1], "part 1 is wrong")
   assert.Equal(t, "two",  parts[2], "part 2 is wrong")
 }
+
+func TestRemoveAccents(t *testing.T) {
+  assert.Equal(t, "mediterraneo", RemoveAccents("mediterrâneo"), "accents not removed")
+  assert.Equal(t, "Espirito Santo", RemoveAccents("Espírito Santo"), "accents not removed")
+  assert.Equal(t, "ressincronizacao", RemoveAccents("ressincronização"), "accents not removed")
+}
+
+func TestEditDistance(t *testing.T) {
+  assert.Equal(t, 0, EditDistance("nelore", "nelore"), "equal strings")
+  assert.Equal(t, 1, EditDistance("nelroe", "nelore"), "swapped letters")
+  assert.Equal(t, 1, EditDistance("angos", "angus"), "replaced letter")
+  assert.Equal(t, 1, EditDistance("angu", "angus"), "missing letter")
+  assert.Equal(t, 6, EditDistance("", "nelore"), "empty string")
+  assert.Equal(t, 1, EditDistance("mediterrâneo", "mediterraneo"), "accents are letters")
+}
+
+func TestSimilarity(t *testing.T) {
+  assert.Equal(t, 1.0, Similarity("Mediterrâneo", "mediterraneo"), "case and accents ignored")
+  assert.InDelta(t, 0.833, Similarity("nelroe", "nelore"), 0.001)
+  assert.InDelta(t, 0.8, Similarity("angos", "angus"), 0.001)
+  assert.InDelta(t, 0.0, Similarity("abc", "xyz"), 0.001)
+}
+
+func TestNormalizeText(t *testing.T) {
+  assert.Equal(t, "espirito santo", NormalizeText("  Espírito-Santo "), "not normalized")
+  assert.Equal(t, "pasto norte 2", NormalizeText("Pasto   Norte, #2!"), "not normalized")
+  assert.Equal(t, "", NormalizeText(" -- "), "not normalized")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+  "time"
+  "posso-help/internal/animal"
+  "posso-help/internal/chat"
+  "posso-help/internal/date"
+  "posso-help/internal/product"
+  "posso-help/internal/scheduler"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Dates are stored in RFC3339 in UTC, the API renders them in the time
+// zone of the account of the user and stores the dates it is sent back
+// in UTC again.
+
+// localizeValue returns RFC3339 and bson dates in the time zone, other
+// values are returned unchanged.
+func localizeValue(value interface{}, loc *time.Location) interface{} {
+  switch v := value.(type) {
+  case string:
+    return date.Localize(v, loc)
+  case primitive.DateTime:
+    return v.Time().In(loc).Format(time.RFC3339)
+  }
+  return value
+}
+
+// normalizeRecord stores the RFC3339 dates of a record written through
+// the API in UTC, so stored dates can be compared as text.
+func normalizeRecord(record map[string]interface{}) {
+  for key, value := range record {
+    if text, ok := value.(string); ok {
+      record[key] = date.Normalize(text)
+    }
+  }
+}
+
+func localizeRecords(records []bson.M, loc *time.Location) {
+  for _, record := range records {
+    for key, value := range record {
+      record[key] = localizeValue(value, loc)
+    }
+  }
+}
+
+func localizeDocuments(documents []bson.D, loc *time.Location) {
+  for _, document := range documents {
+    for index := range document {
+      document[index].Value = localizeValue(document[index].Value, loc)
+    }
+  }
+}
+
+func localizeWithdrawals(withdrawals []*product.Withdrawal, loc *time.Location) {
+  for _, withdrawal := range withdrawals {
+    withdrawal.Date = date.Localize(withdrawal.Date, loc)
+    withdrawal.WithdrawalUntil = date.Localize(withdrawal.WithdrawalUntil, loc)
+  }
+}
+
+func localizeAnimals(animals []*animal.Animal, loc *time.Location) {
+  for _, a := range animals {
+    a.BirthDate = date.Localize(a.BirthDate, loc)
+    a.DeathDate = date.Localize(a.DeathDate, loc)
+    a.UpdatedAt = a.UpdatedAt.In(loc)
+  }
+}
+
+func localizeTimeline(events []*chat.TimelineEvent, loc *time.Location) {
+  for _, event := range events {
+    event.Date = date.Localize(event.Date, loc)
+    localizeRecords([]bson.M{event.Record}, loc)
+    if event.Message != nil {
+      event.Message.Date = date.Localize(event.Message.Date, loc)
+    }
+  }
+}
+
+func localizeTasks(tasks []*scheduler.Task, loc *time.Location) {
+  for _, task := range tasks {
+    task.DueAt = task.DueAt.In(loc)
+    task.CreatedAt = task.CreatedAt.In(loc)
+    if task.SentAt != nil {
+      sentAt := task.SentAt.In(loc)
+      task.SentAt = &sentAt
+    }
+  }
+}
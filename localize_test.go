@@ -0,0 +1,38 @@
+package main
+
+import (
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+  "go.mongodb.org/mongo-driver/bson"
+  "go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLocalizeDocuments(t *testing.T) {
+  saoPaulo := time.FixedZone("BRT", -3 * 60 * 60)
+  created := time.Date(2025, time.September, 15, 1, 30, 0, 0, time.UTC)
+  documents := []bson.D{{
+    {Key: "date", Value: "2025-09-15T01:30:00Z"},
+    {Key: "created_at", Value: primitive.NewDateTimeFromTime(created)},
+    {Key: "area", Value: "pasto norte"},
+    {Key: "tag", Value: 1234},
+  }}
+  localizeDocuments(documents, saoPaulo)
+  assert.Equal(t, "2025-09-14T22:30:00-03:00", documents[0][0].Value)
+  assert.Equal(t, "2025-09-14T22:30:00-03:00", documents[0][1].Value)
+  assert.Equal(t, "pasto norte", documents[0][2].Value)
+  assert.Equal(t, 1234, documents[0][3].Value)
+}
+
+func TestLocalizeRecords(t *testing.T) {
+  records := []bson.M{{"date": "2025-09-15T01:30:00Z"}}
+  localizeRecords(records, time.UTC)
+  assert.Equal(t, "2025-09-15T01:30:00Z", records[0]["date"])
+}
+
+func TestNormalizeRecord(t *testing.T) {
+  record := bson.M{"date": "2025-09-14T22:30:00-03:00", "area": "pasto norte", "tag": 1234}
+  normalizeRecord(record)
+  assert.Equal(t, bson.M{"date": "2025-09-15T01:30:00Z", "area": "pasto norte", "tag": 1234}, record,
+               "Dates sent back by the API are stored in UTC")
+}
@@ -10,6 +10,8 @@ import (
   "sync"
   "time"
 
+  "posso-help/internal/scheduler"
+
   "github.com/gorilla/mux"
 )
 
@@ -103,6 +105,31 @@ func main() {
   uploadRouter.HandleFunc("/{datatype}", HandleUpload).Methods("POST")
   uploadRouter.HandleFunc("/{datatype}/json", HandleUploadJSON).Methods("POST")
 
+  // Treatment routes
+  treatmentRouter := r.PathPrefix("/api/treatments").Subrouter()
+  treatmentRouter.Use(AuthMiddleware)
+  treatmentRouter.HandleFunc("/withdrawals", HandleWithdrawalsGet).Methods("GET")
+
+  // Scheduled task routes
+  taskRouter := r.PathPrefix("/api/tasks").Subrouter()
+  taskRouter.Use(AuthMiddleware)
+  taskRouter.HandleFunc("", HandleTasksGet).Methods("GET")
+  taskRouter.HandleFunc("", HandleTasksPost).Methods("POST")
+
+  // Unparsed message triage routes
+  messageRouter := r.PathPrefix("/api/messages").Subrouter()
+  messageRouter.Use(AuthMiddleware)
+  messageRouter.HandleFunc("/unparsed", HandleUnparsedMessagesGet).Methods("GET")
+  messageRouter.HandleFunc("/unparsed/{id}/resolve", HandleUnparsedMessageResolve).Methods("PUT")
+
+  // Animal registry and lineage routes
+  animalRouter := r.PathPrefix("/api/animals").Subrouter()
+  animalRouter.Use(AuthMiddleware)
+  animalRouter.HandleFunc("", HandleAnimalsGet).Methods("GET")
+  animalRouter.HandleFunc("/rebuild", HandleAnimalsRebuild).Methods("POST")
+  animalRouter.HandleFunc("/{tag}/pedigree", HandlePedigreeGet).Methods("GET")
+  animalRouter.HandleFunc("/{tag}/timeline", HandleTimelineGet).Methods("GET")
+
   // User routes
   userRouter := r.PathPrefix("/api/user").Subrouter()
   userRouter.Use(AuthMiddleware)
@@ -134,6 +161,9 @@ func main() {
     fmt.Fprint(w, `{"status":"success"}`)
   })
 
+  // Send due reminders in the background
+  scheduler.Start(time.Minute)
+
   log.Println("Starting Server")
   log.Fatal(http.ListenAndServe(":8080", r))
 }